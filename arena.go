@@ -0,0 +1,45 @@
+package rdx
+
+// Arena is a bump allocator for bulk strings read by a Reader. When a Reader has an Arena, the
+// payloads of bulk strings it reads are copied into the Arena instead of being allocated
+// individually, so that all strings read between calls to Reset share a small number of
+// backing buffers.
+//
+// Strings allocated from an Arena alias its memory. After Reset, the Arena reuses its most
+// recent buffer, overwriting any strings previously allocated from it. Callers must not retain
+// strings read through an Arena past a call to Reset. An Arena is not safe for concurrent use.
+type Arena struct {
+	buf []byte
+}
+
+// NewArena allocates a new Arena with an initial capacity of size bytes.
+func NewArena(size int) *Arena {
+	return &Arena{buf: make([]byte, 0, size)}
+}
+
+// Len returns the number of bytes allocated from the Arena's current buffer.
+func (a *Arena) Len() int {
+	return len(a.buf)
+}
+
+// Reset releases all allocations made from the Arena. The Arena's current buffer is reused by
+// subsequent allocations, invalidating any strings previously allocated from it.
+func (a *Arena) Reset() {
+	a.buf = a.buf[:0]
+}
+
+// alloc returns a slice of n bytes from the Arena. If the current buffer does not have room
+// for n bytes, a new buffer is allocated and the previous one is left to any strings still
+// referencing it.
+func (a *Arena) alloc(n int) []byte {
+	if cap(a.buf)-len(a.buf) < n {
+		size := cap(a.buf) * 2
+		if size < n {
+			size = n
+		}
+		a.buf = make([]byte, 0, size)
+	}
+	off := len(a.buf)
+	a.buf = a.buf[:off+n]
+	return a.buf[off : off+n : off+n]
+}
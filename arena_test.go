@@ -0,0 +1,105 @@
+package rdx_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_Arena(t *testing.T) {
+	const stream = "*3\r\n$3\r\nfoo\r\n$0\r\n\r\n$3\r\nbar\r\n" +
+		"$3\r\nbaz\r\n"
+
+	arena := rdx.NewArena(16)
+	r := rdx.NewReader(strings.NewReader(stream))
+	r.Arena = arena
+
+	first, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v", err)
+	}
+	want := rdx.Array{rdx.String("foo"), rdx.String{}, rdx.String("bar")}
+	if !reflect.DeepEqual(first, want) {
+		t.Fatalf("Read() = %v; want %v", first, want)
+	}
+	if got := arena.Len(); got != 6 {
+		t.Fatalf("arena.Len() = %d; want 6", got)
+	}
+
+	// Strings share the arena's buffer until it is reset, after which new strings overwrite
+	// them.
+	arena.Reset()
+	second, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v", err)
+	}
+	if second.String() != "baz" {
+		t.Fatalf("Read() = %v; want baz", second)
+	}
+	if got := first.(rdx.Array)[0].String(); got != "baz" {
+		t.Fatalf("first string after reset = %q; want %q", got, "baz")
+	}
+}
+
+func TestReader_ArenaGrow(t *testing.T) {
+	arena := rdx.NewArena(2)
+	r := rdx.NewReader(strings.NewReader("$3\r\nfoo\r\n$5\r\nhello\r\n$3\r\nbar\r\n"))
+	r.Arena = arena
+
+	var got []rdx.Msg
+	for i := 0; i < 3; i++ {
+		msg, err := r.Read()
+		if err != nil {
+			t.Fatalf("[%d] Read() err = %v", i, err)
+		}
+		got = append(got, msg)
+	}
+
+	// Strings allocated before the arena grew keep their contents.
+	want := []rdx.Msg{rdx.String("foo"), rdx.String("hello"), rdx.String("bar")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Read() = %v; want %v", got, want)
+	}
+}
+
+func TestReader_ArenaMissingCRLF(t *testing.T) {
+	r := rdx.NewReader(strings.NewReader("$3\r\nfooxx"))
+	r.Arena = rdx.NewArena(0)
+	if _, err := r.Read(); err != rdx.ErrMissingCRLF {
+		t.Fatalf("Read() err = %v; want %v", err, rdx.ErrMissingCRLF)
+	}
+}
+
+func benchmarkArena(b *testing.B, arena *rdx.Arena) {
+	var stream bytes.Buffer
+	args := make(rdx.Array, 16)
+	for i := range args {
+		args[i] = rdx.BulkString(strings.Repeat("x", 32))
+	}
+	if _, err := rdx.Write(&stream, args); err != nil {
+		b.Fatal(err)
+	}
+	msg := stream.Bytes()
+
+	br := bytes.NewReader(msg)
+	r := rdx.NewReader(br)
+	r.Arena = arena
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(msg)))
+	for i := 0; i < b.N; i++ {
+		br.Reset(msg)
+		if arena != nil {
+			arena.Reset()
+		}
+		if _, err := r.Read(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReader_NoArena(b *testing.B) { benchmarkArena(b, nil) }
+func BenchmarkReader_Arena(b *testing.B)   { benchmarkArena(b, rdx.NewArena(4096)) }
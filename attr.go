@@ -0,0 +1,75 @@
+package rdx
+
+import (
+	"bytes"
+	"io"
+)
+
+// Attributed is a message preceded by RESP3 attributes, out-of-band metadata about a reply
+// such as the popularity of the keys it contains. The decoder returns an Attributed for any
+// message, top-level or nested, that is preceded by an attribute map.
+//
+// An Attributed has the type and string form of its Msg, and Equal, the To functions (such as
+// ToInt and ToArray), and Unmarshal look through it to its Msg. Callers that don't use
+// attributes can discard them with StripAttrs.
+type Attributed struct {
+	Attrs Map
+	Msg   Msg
+}
+
+var _ Msg = Attributed{}
+
+func (a Attributed) Type() Type     { return ensure(a.Msg).Type() }
+func (a Attributed) String() string { return ensure(a.Msg).String() }
+
+func (a Attributed) estlen() int {
+	sz := a.Attrs.estlen()
+	if em, ok := ensure(a.Msg).(estlen); ok {
+		sz += em.estlen()
+	}
+	return sz
+}
+
+func (a Attributed) writeTo(buf *bytes.Buffer) (err error) {
+	if err = writePairs(buf, '|', a.Attrs); err != nil {
+		return err
+	}
+	return writeElem(buf, a.Msg)
+}
+
+func (a Attributed) WriteTo(w io.Writer) (n int64, err error) {
+	buf := tempbuffer(a.estlen())
+	defer putbuffer(buf)
+	if err = a.writeTo(buf); err != nil {
+		return 0, err
+	}
+
+	return buf.WriteTo(w)
+}
+
+// StripAttrs returns the message underlying m if m is an Attributed, and otherwise returns m.
+// Only the attributes of m itself are removed, not those of messages nested in it.
+func StripAttrs(m Msg) Msg {
+	for {
+		a, ok := m.(Attributed)
+		if !ok {
+			return m
+		}
+		m = a.Msg
+	}
+}
+
+// readAttributed reads an attribute map followed by the message it describes.
+func (r *Reader) readAttributed(head []byte) (Msg, error) {
+	attrs, err := r.readMap(head)
+	if err != nil {
+		return nil, err
+	}
+	pairs, _ := attrs.(Map)
+
+	msg, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	return Attributed{Attrs: pairs, Msg: msg}, nil
+}
@@ -0,0 +1,94 @@
+package rdx_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_ReadAttributed(t *testing.T) {
+	popularity := rdx.Map{
+		{Key: rdx.String("key-popularity"), Value: rdx.Map{
+			{Key: rdx.String("a"), Value: rdx.Double(0.1923)},
+		}},
+	}
+
+	table := []dectest{
+		{msg: "|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.1923\r\n*2\r\n:2039123\r\n:9543892\r\n",
+			typ: rdx.TArray,
+			result: rdx.Attributed{
+				Attrs: popularity,
+				Msg:   rdx.Array{rdx.Int(2039123), rdx.Int(9543892)},
+			}},
+		{msg: "*2\r\n:1\r\n|1\r\n+ttl\r\n:3600\r\n:2\r\n",
+			typ: rdx.TArray,
+			result: rdx.Array{
+				rdx.Int(1),
+				rdx.Attributed{Attrs: rdx.Map{{Key: rdx.String("ttl"), Value: rdx.Int(3600)}}, Msg: rdx.Int(2)},
+			}},
+		{msg: "|0\r\n$1\r\nx\r\n", typ: rdx.TBulkString, result: rdx.Attributed{Msg: rdx.String("x")}},
+		{msg: "|1\r\n+a\r\n:1\r\n", err: io.EOF},
+		{msg: "|1\r\n+a\r\n", err: rdx.ErrOddMapLength},
+	}
+
+	for i, d := range table {
+		d.eval(t, i)
+	}
+}
+
+func TestAttributed(t *testing.T) {
+	msg := rdx.Attributed{
+		Attrs: rdx.Map{{Key: rdx.BulkString("k"), Value: rdx.Int(1)}},
+		Msg:   rdx.Array{rdx.Int(2), rdx.Attributed{Attrs: rdx.Map{}, Msg: rdx.BulkString("3")}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := rdx.Write(&buf, msg); err != nil {
+		t.Fatalf("Write() err = %v", err)
+	}
+	const want = "|1\r\n$1\r\nk\r\n:1\r\n*2\r\n:2\r\n|0\r\n$1\r\n3\r\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("Write() wrote %q; want %q", got, want)
+	}
+
+	got, err := newRESP3Reader(&buf).Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v", err)
+	}
+	if attrs := got.(rdx.Attributed).Attrs; !rdx.Equal(attrs, msg.Attrs) {
+		t.Errorf("Read() attributes = %v; want %v", attrs, msg.Attrs)
+	}
+
+	// Equal and the conversion functions look through attributes.
+	plain := rdx.Array{rdx.Int(2), rdx.String("3")}
+	if !rdx.Equal(got, plain) {
+		t.Errorf("Equal(%v, %v) = false; want true", got, plain)
+	}
+	if stripped := rdx.StripAttrs(got); !reflect.DeepEqual(stripped, rdx.Array{rdx.Int(2), rdx.Attributed{Msg: rdx.String("3")}}) {
+		t.Errorf("StripAttrs() = %#v; want the array with its nested attributes", stripped)
+	}
+	elems, ok := rdx.ToArray(got)
+	if !ok || len(elems) != 2 {
+		t.Fatalf("ToArray() = %v, %v; want 2 elements, true", elems, ok)
+	}
+	if n, err := rdx.ToInt(elems[1]); err != nil || n != 3 {
+		t.Errorf("ToInt(%v) = %d, %v; want 3, nil", elems[1], n, err)
+	}
+
+	var dst []int
+	if err := rdx.Unmarshal(got, &dst); err != nil || !reflect.DeepEqual(dst, []int{2, 3}) {
+		t.Errorf("Unmarshal() = %v, %v; want [2 3], nil", dst, err)
+	}
+
+	errMsg := rdx.Attributed{Msg: rdx.Error("ERR x")}
+	if err := rdx.ToError(errMsg); err != rdx.Error("ERR x") {
+		t.Errorf("ToError(%v) = %v; want ERR x", errMsg, err)
+	}
+
+	if got := rdx.StripAttrs(rdx.Int(1)); got != rdx.Int(1) {
+		t.Errorf("StripAttrs(1) = %v; want 1", got)
+	}
+}
@@ -1,14 +1,65 @@
 package rdx
 
 import (
+	"bufio"
 	"bytes"
+	"math"
 	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
+// Default capacities of the buffers used to encode messages. See SetBufferCaps.
+const (
+	DefaultBufferMinCap = 80
+	DefaultBufferMaxCap = 4096 * 8
+)
+
+// bufferMinCap and bufferMaxCap hold the capacities set by SetBufferCaps, or zero for the
+// defaults. They are only accessed with sync/atomic.
+var bufferMinCap, bufferMaxCap int64
+
+// SetBufferCaps sets the capacities of the pooled buffers used to encode messages, such as by
+// WriteTo and WriteAll. New buffers are allocated with a capacity of minCap bytes, and buffers
+// that have grown beyond maxCap bytes are discarded after use instead of being returned to the
+// pool. The encoding buffers kept by a Writer or StreamingEncoder are likewise discarded once
+// they grow beyond maxCap bytes. Raising maxCap lets workloads that consistently encode large
+// messages reuse their buffers, at the cost of keeping more memory in the pool. A minCap or
+// maxCap of zero or less restores its default, DefaultBufferMinCap or DefaultBufferMaxCap, and
+// a maxCap less than minCap is raised to minCap.
+//
+// SetBufferCaps is safe to call concurrently with encoding. Buffers already in the pool are
+// kept regardless of their capacity.
+func SetBufferCaps(minCap, maxCap int) {
+	if minCap <= 0 {
+		minCap = DefaultBufferMinCap
+	}
+	if maxCap <= 0 {
+		maxCap = DefaultBufferMaxCap
+	}
+	if maxCap < minCap {
+		maxCap = minCap
+	}
+	atomic.StoreInt64(&bufferMinCap, int64(minCap))
+	atomic.StoreInt64(&bufferMaxCap, int64(maxCap))
+}
+
+// BufferCaps returns the capacities of pooled buffers set by SetBufferCaps.
+func BufferCaps() (minCap, maxCap int) {
+	minCap = int(atomic.LoadInt64(&bufferMinCap))
+	maxCap = int(atomic.LoadInt64(&bufferMaxCap))
+	if minCap == 0 {
+		minCap = DefaultBufferMinCap
+	}
+	if maxCap == 0 {
+		maxCap = DefaultBufferMaxCap
+	}
+	return minCap, maxCap
+}
+
 var buffers = sync.Pool{
 	New: func() interface{} {
-		const mincap = 80
+		mincap, _ := BufferCaps()
 		return bytes.NewBuffer(make([]byte, 0, mincap))
 	},
 }
@@ -21,25 +72,64 @@ func tempbuffer(cap int) *bytes.Buffer {
 
 func putbuffer(b *bytes.Buffer) {
 	// This could become a problem if enormous payloads are always being sent, but should only
-	// occur when sending huge strings or arrays.
-	const maxcap = 4096 * 8
-	if b.Cap() > maxcap {
+	// occur when sending huge strings or arrays, unless the maximum is raised to allow it.
+	if _, maxcap := BufferCaps(); b.Cap() > maxcap {
 		return
 	}
 	b.Reset()
 	buffers.Put(b)
 }
 
+// trimbuffer releases the storage of a long-lived encoding buffer, such as a Writer's, if it
+// has grown beyond the maximum capacity set by SetBufferCaps.
+func trimbuffer(b *bytes.Buffer) {
+	if _, maxcap := BufferCaps(); b.Cap() > maxcap {
+		*b = bytes.Buffer{}
+	}
+}
+
+// putint writes prefix, n, and a CRLF to buf, returning the number of bytes written. The line
+// is built in a stack array sized for the longest int64, so it doesn't allocate.
 func putint(buf *bytes.Buffer, prefix byte, n int64) int64 {
-	tmp := [23]byte{prefix}
-	b := strconv.AppendInt(tmp[:1], n, 10)
-	b = append(b, "\r\n"...)
-	buf.Write(b)
-	return int64(len(b))
+	// prefix + "-9223372036854775808" + CRLF
+	var tmp [1 + 20 + 2]byte
+	tmp[0] = prefix
+	i := len(strconv.AppendInt(tmp[:1], n, 10))
+	tmp[i], tmp[i+1] = '\r', '\n'
+	buf.Write(tmp[:i+2])
+	return int64(i + 2)
+}
+
+// writeFrame writes a string frame holding prefix, the length of s if withLen is true, and s
+// directly to w. Since w buffers the frame itself, this avoids assembling it in a pooled buffer
+// first, and the length is formatted into w's free space so that nothing is allocated.
+func writeFrame(w *bufio.Writer, prefix byte, withLen bool, s string) (n int64, err error) {
+	if err = w.WriteByte(prefix); err != nil {
+		return 0, err
+	}
+	n = 1
+	if withLen {
+		b := strconv.AppendInt(w.AvailableBuffer(), int64(len(s)), 10)
+		b = append(b, '\r', '\n')
+		nw, err := w.Write(b)
+		if n += int64(nw); err != nil {
+			return n, err
+		}
+	}
+	nw, err := w.WriteString(s)
+	if n += int64(nw); err != nil {
+		return n, err
+	}
+	nw, err = w.WriteString("\r\n")
+	return n + int64(nw), err
 }
 
 func intlen(i int64) (n int) {
 	if i < 0 {
+		if i == math.MinInt64 {
+			// -i overflows, so count its digits directly.
+			return 20
+		}
 		n++
 		i = -i
 	}
@@ -0,0 +1,96 @@
+package rdx_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestSetBufferCaps(t *testing.T) {
+	t.Cleanup(func() { rdx.SetBufferCaps(0, 0) })
+
+	for i, c := range []struct {
+		min, max         int
+		wantMin, wantMax int
+	}{
+		{0, 0, rdx.DefaultBufferMinCap, rdx.DefaultBufferMaxCap},
+		{256, 1 << 20, 256, 1 << 20},
+		{-1, 1 << 20, rdx.DefaultBufferMinCap, 1 << 20},
+		{512, 0, 512, rdx.DefaultBufferMaxCap},
+		{4096, 1024, 4096, 4096},
+		{0, 0, rdx.DefaultBufferMinCap, rdx.DefaultBufferMaxCap},
+	} {
+		rdx.SetBufferCaps(c.min, c.max)
+		if min, max := rdx.BufferCaps(); min != c.wantMin || max != c.wantMax {
+			t.Errorf("[%d] SetBufferCaps(%d, %d): BufferCaps() = %d, %d; want %d, %d",
+				i, c.min, c.max, min, max, c.wantMin, c.wantMax)
+		}
+	}
+}
+
+func TestSetBufferCaps_Concurrent(t *testing.T) {
+	t.Cleanup(func() { rdx.SetBufferCaps(0, 0) })
+
+	msg := rdx.Array{rdx.BulkString(strings.Repeat("x", 64<<10)), rdx.Int(1)}
+	want, err := rdx.Bytes(msg)
+	if err != nil {
+		t.Fatalf("Bytes() err = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var buf bytes.Buffer
+			for j := 0; j < 50; j++ {
+				if i == 0 {
+					rdx.SetBufferCaps(j*16, j<<12)
+				}
+				buf.Reset()
+				if _, err := rdx.Write(&buf, msg); err != nil || !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("Write() = %v; wrote %d bytes, want %d", err, buf.Len(), len(want))
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSetBufferCaps_Writers(t *testing.T) {
+	t.Cleanup(func() { rdx.SetBufferCaps(0, 0) })
+
+	// A message larger than the default maximum but within the raised one.
+	msg := rdx.BulkString(strings.Repeat("x", rdx.DefaultBufferMaxCap*2))
+	for _, c := range []struct {
+		max  int
+		keep bool
+	}{
+		{0, false},
+		{rdx.DefaultBufferMaxCap * 4, true},
+		{1024, false},
+	} {
+		rdx.SetBufferCaps(0, c.max)
+
+		w := rdx.NewWriter(io.Discard)
+		if err := w.WriteMsg(msg); err != nil {
+			t.Fatalf("Writer.WriteMsg() err = %v", err)
+		}
+		if kept := w.ScratchCap() > 0; kept != c.keep {
+			t.Errorf("max=%d: Writer kept buffer of cap %d; want kept = %t", c.max, w.ScratchCap(), c.keep)
+		}
+
+		e := rdx.NewStreamingEncoder(io.Discard)
+		if err := e.WriteMsg(msg); err != nil {
+			t.Fatalf("StreamingEncoder.WriteMsg() err = %v", err)
+		}
+		if kept := e.ScratchCap() > 0; kept != c.keep {
+			t.Errorf("max=%d: StreamingEncoder kept buffer of cap %d; want kept = %t", c.max, e.ScratchCap(), c.keep)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package rdx
+
+// Builder builds an Array one element at a time. Its methods return the Builder so that calls
+// can be chained:
+//
+//	reply := new(rdx.Builder).Str("OK").Int(1).Msg(nested).Build()
+//
+// The zero Builder is empty and ready to use.
+type Builder struct {
+	elems Array
+}
+
+// Int appends n as an Int.
+func (b *Builder) Int(n int64) *Builder {
+	b.elems = append(b.elems, Int(n))
+	return b
+}
+
+// Str appends s as a BulkString.
+func (b *Builder) Str(s string) *Builder {
+	b.elems = append(b.elems, BulkString(s))
+	return b
+}
+
+// Bulk appends p as a String. p is not copied.
+func (b *Builder) Bulk(p []byte) *Builder {
+	b.elems = append(b.elems, String(p))
+	return b
+}
+
+// Err appends s as an Error.
+func (b *Builder) Err(s string) *Builder {
+	b.elems = append(b.elems, Error(s))
+	return b
+}
+
+// Msg appends m, such as a nested Array built by another Builder.
+func (b *Builder) Msg(m Msg) *Builder {
+	b.elems = append(b.elems, m)
+	return b
+}
+
+// Len returns the number of elements appended so far.
+func (b *Builder) Len() int {
+	return len(b.elems)
+}
+
+// Build returns the Array of elements appended so far. If none have been appended, it returns
+// Array(nil), which encodes as an empty array. The Builder is reset, so it can be used to build
+// another Array without affecting the one returned.
+func (b *Builder) Build() Array {
+	a := b.elems
+	b.elems = nil
+	return a
+}
@@ -0,0 +1,54 @@
+package rdx_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestArray_Append(t *testing.T) {
+	a := rdx.Array{rdx.Int(1)}.Append(rdx.Int(2), rdx.BulkString("x"))
+	want := rdx.Array{rdx.Int(1), rdx.Int(2), rdx.BulkString("x")}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("Append() = %v; want %v", a, want)
+	}
+	if got := rdx.Array(nil).Append(); got != nil {
+		t.Errorf("Array(nil).Append() = %#v; want nil", got)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	var b rdx.Builder
+	if got := b.Build(); got != nil {
+		t.Fatalf("Build() = %#v; want Array(nil)", got)
+	}
+
+	var buf bytes.Buffer
+	if _, err := rdx.Write(&buf, b.Build()); err != nil || buf.String() != "*0\r\n" {
+		t.Fatalf("Write(Build()) wrote %q, %v; want %q, nil", buf.String(), err, "*0\r\n")
+	}
+
+	nested := new(rdx.Builder).Int(2).Build()
+	got := b.Int(1).Str("a").Bulk([]byte("b")).Err("ERR x").Msg(nested).Build()
+	want := rdx.Array{
+		rdx.Int(1),
+		rdx.BulkString("a"),
+		rdx.String("b"),
+		rdx.Error("ERR x"),
+		rdx.Array{rdx.Int(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Build() = %#v; want %#v", got, want)
+	}
+
+	// Build resets the builder without affecting the returned array.
+	if n := b.Len(); n != 0 {
+		t.Fatalf("Len() = %d after Build; want 0", n)
+	}
+	b.Int(3)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Build() result changed to %#v after reuse", got)
+	}
+}
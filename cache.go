@@ -0,0 +1,96 @@
+package rdx
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// PreEncoded is a message whose wire form was encoded once by Cache. Writing a PreEncoded
+// message writes the cached bytes without encoding the original message again, which makes it
+// suitable for replies that are sent repeatedly, such as fixed errors. A PreEncoded message is
+// immutable and may be shared between goroutines and connections.
+type PreEncoded struct {
+	msg  Msg
+	wire []byte
+}
+
+// maxCached is the maximum number of messages kept by Cache.
+const maxCached = 1024
+
+// cached holds the PreEncoded form of messages passed to Cache, keyed by the message itself.
+var cached struct {
+	sync.RWMutex
+	msgs map[Msg]PreEncoded
+}
+
+// Cache returns a PreEncoded message holding the wire form of m. The result can be used
+// anywhere a Msg is expected and should be kept and reused in place of m. If m cannot be
+// encoded, Cache returns its error. Caching a PreEncoded message returns it as-is.
+//
+// Scalar messages that compare equal, such as the same Error or Int, are encoded once and
+// share their PreEncoded form across calls, up to a fixed number of distinct messages.
+// Aggregates are encoded on each call.
+func Cache(m Msg) (Msg, error) {
+	if pe, ok := m.(PreEncoded); ok {
+		return pe, nil
+	}
+
+	m = ensure(m)
+	keyed := cacheable(m)
+	if keyed {
+		cached.RLock()
+		pe, ok := cached.msgs[m]
+		cached.RUnlock()
+		if ok {
+			return pe, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	if em, ok := m.(estlen); ok {
+		buf.Grow(em.estlen())
+	}
+	if err := writeElem(&buf, m); err != nil {
+		return nil, err
+	}
+	pe := PreEncoded{msg: m, wire: buf.Bytes()}
+
+	if keyed {
+		cached.Lock()
+		if prev, ok := cached.msgs[m]; ok {
+			pe = prev
+		} else if len(cached.msgs) < maxCached {
+			if cached.msgs == nil {
+				cached.msgs = make(map[Msg]PreEncoded)
+			}
+			cached.msgs[m] = pe
+		}
+		cached.Unlock()
+	}
+	return pe, nil
+}
+
+// cacheable reports whether m can be used as a key of the Cache map. Floats are excluded
+// because NaN is never equal to itself.
+func cacheable(m Msg) bool {
+	switch m.(type) {
+	case nilmsg, Int, Bool, Error, SimpleString, BulkString:
+		return true
+	}
+	return false
+}
+
+var _ Msg = PreEncoded{}
+
+// Msg returns the message that was encoded.
+func (p PreEncoded) Msg() Msg { return p.msg }
+
+func (p PreEncoded) Type() Type     { return p.msg.Type() }
+func (p PreEncoded) String() string { return p.msg.String() }
+func (p PreEncoded) estlen() int    { return len(p.wire) }
+
+func (p PreEncoded) WriteTo(w io.Writer) (n int64, err error) {
+	in, err := w.Write(p.wire)
+	return int64(in), err
+}
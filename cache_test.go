@@ -0,0 +1,138 @@
+package rdx_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestCache(t *testing.T) {
+	for i, m := range []rdx.Msg{
+		rdx.Error("ERR unknown command"),
+		rdx.SimpleString("OK"),
+		rdx.Int(1),
+		nil,
+		rdx.Array{rdx.BulkString("a"), rdx.Map{{Key: rdx.Int(1), Value: rdx.Nil}}},
+	} {
+		var want bytes.Buffer
+		rdx.Write(&want, m)
+
+		pe, err := rdx.Cache(m)
+		if err != nil {
+			t.Errorf("[%d] Cache(%v) err = %v", i, m, err)
+			continue
+		}
+		if got := pe.(rdx.PreEncoded).Msg(); !rdx.Equal(got, m) {
+			t.Errorf("[%d] Cache(%v).Msg() = %v", i, m, got)
+		}
+		if m != nil && pe.Type() != m.Type() {
+			t.Errorf("[%d] Cache(%v).Type() = %v; want %v", i, m, pe.Type(), m.Type())
+		}
+
+		// Written alone and nested in an aggregate.
+		var got, nested, wantNested bytes.Buffer
+		rdx.Write(&got, pe)
+		rdx.Write(&nested, rdx.Array{pe})
+		rdx.Write(&wantNested, rdx.Array{m})
+		if got.String() != want.String() {
+			t.Errorf("[%d] Write(Cache(%v)) = %q; want %q", i, m, got.String(), want.String())
+		}
+		if nested.String() != wantNested.String() {
+			t.Errorf("[%d] Write([Cache(%v)]) = %q; want %q", i, m, nested.String(), wantNested.String())
+		}
+
+		if again, err := rdx.Cache(pe); err != nil || !rdx.Equal(again, pe) {
+			t.Errorf("[%d] Cache(Cache(%v)) = %v, %v; want %v, nil", i, m, again, err, pe)
+		}
+	}
+
+	if _, err := rdx.Cache(rdx.Error("\n")); err != rdx.ErrInvalidError {
+		t.Errorf("Cache(invalid) err = %v; want %v", err, rdx.ErrInvalidError)
+	}
+}
+
+func TestCache_keyed(t *testing.T) {
+	// Scalars share their encoded form across calls.
+	for i, m := range []rdx.Msg{
+		rdx.Error("ERR cached"),
+		rdx.SimpleString("QUEUED"),
+		rdx.BulkString("cached"),
+		rdx.Int(-12345),
+		rdx.Bool(true),
+		nil,
+	} {
+		a, err := rdx.Cache(m)
+		if err != nil {
+			t.Fatalf("[%d] Cache(%v) err = %v", i, m, err)
+		}
+		b, _ := rdx.Cache(m)
+		if wa, wb := wireOf(t, a), wireOf(t, b); &wa[0] != &wb[0] {
+			t.Errorf("[%d] Cache(%v) encoded %v again", i, m, m)
+		}
+	}
+
+	// Aggregates are encoded each time.
+	m := rdx.Array{rdx.Int(1)}
+	a, _ := rdx.Cache(m)
+	m[0] = rdx.Int(2)
+	b, _ := rdx.Cache(m)
+	if got := string(wireOf(t, b)); got != "*1\r\n:2\r\n" {
+		t.Errorf("Cache(%v) = %q; want re-encoded array", m, got)
+	} else if got := string(wireOf(t, a)); got != "*1\r\n:1\r\n" {
+		t.Errorf("Cache(%v) = %q; want original array", m, got)
+	}
+}
+
+// wireOf returns the bytes that pe writes without copying them.
+func wireOf(t *testing.T, pe rdx.Msg) []byte {
+	t.Helper()
+	var w captureWriter
+	if _, err := pe.WriteTo(&w); err != nil {
+		t.Fatalf("WriteTo() err = %v", err)
+	}
+	return w.p
+}
+
+// captureWriter keeps the last slice passed to Write.
+type captureWriter struct{ p []byte }
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.p = p
+	return len(p), nil
+}
+
+var benchReply = rdx.Array{
+	rdx.Error("WRONGTYPE Operation against a key holding the wrong kind of value"),
+	rdx.BulkString("value"),
+	rdx.Int(42),
+}
+
+func BenchmarkWrite_Uncached(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rdx.Write(ioutil.Discard, benchReply)
+	}
+}
+
+func BenchmarkWrite_Cached(b *testing.B) {
+	cached, err := rdx.Cache(benchReply)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rdx.Write(ioutil.Discard, cached)
+	}
+}
+
+func BenchmarkCache_Error(b *testing.B) {
+	e := benchReply[0]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pe, _ := rdx.Cache(e)
+		rdx.Write(ioutil.Discard, pe)
+	}
+}
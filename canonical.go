@@ -0,0 +1,79 @@
+package rdx
+
+import "bytes"
+
+// Canonicalize decodes the stream of messages in b and re-encodes them in a canonical form, so
+// that semantically equal streams canonicalize to identical bytes. In canonical form:
+//
+//   - Simple and bulk strings are encoded as bulk strings.
+//   - All nil forms (null bulk strings, null arrays, and RESP3 nulls) are encoded as null bulk
+//     strings.
+//   - Integers, doubles, and big numbers are encoded without redundant signs or leading zeros.
+//   - Errors and aggregates keep their type, with their elements canonicalized.
+//   - Attributes are kept, with their keys and values canonicalized.
+//
+// If b ends partway through a message, Canonicalize returns io.ErrUnexpectedEOF.
+func Canonicalize(b []byte) ([]byte, error) {
+	r := NewReader(bytes.NewReader(b))
+	r.SetProtocol(RESP3)
+	msgs, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(b))
+	for _, msg := range msgs {
+		if err = writeElem(&buf, canonical(msg)); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// canonical returns the canonical form of m.
+func canonical(m Msg) Msg {
+	switch m := ensure(m).(type) {
+	case String:
+		return m
+	case BulkString:
+		return String(m)
+	case SimpleString:
+		return String(m)
+	case Float64:
+		return String(m.String())
+	case nilmsg:
+		return Nil
+	case Array:
+		return Array(canonicalElems(m))
+	case Set:
+		return Set(canonicalElems(m))
+	case Push:
+		return Push(canonicalElems(m))
+	case Map:
+		return canonicalPairs(m)
+	case Attributed:
+		return Attributed{Attrs: canonicalPairs(m.Attrs), Msg: canonical(m.Msg)}
+	default:
+		return m
+	}
+}
+
+func canonicalPairs(m Map) Map {
+	pairs := make(Map, len(m))
+	for i, p := range m {
+		pairs[i] = Pair{Key: canonical(p.Key), Value: canonical(p.Value)}
+	}
+	return pairs
+}
+
+func canonicalElems(msgs []Msg) []Msg {
+	if len(msgs) == 0 {
+		return nil
+	}
+	elems := make([]Msg, len(msgs))
+	for i, m := range msgs {
+		elems[i] = canonical(m)
+	}
+	return elems
+}
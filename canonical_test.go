@@ -0,0 +1,53 @@
+package rdx_test
+
+import (
+	"io"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestCanonicalize(t *testing.T) {
+	table := []struct {
+		a, b string
+	}{
+		{"+OK\r\n", "$2\r\nOK\r\n"},
+		{":007\r\n:-0\r\n", ":7\r\n:0\r\n"},
+		{"*-1\r\n_\r\n", "$-1\r\n$-1\r\n"},
+		{"*2\r\n+a\r\n:01\r\n", "*2\r\n$1\r\na\r\n:1\r\n"},
+		{"%1\r\n+k\r\n~1\r\n+v\r\n", "%1\r\n$1\r\nk\r\n~1\r\n$1\r\nv\r\n"},
+		{",1.50\r\n(0012\r\n", ",1.5\r\n(12\r\n"},
+		{"*0\r\n-ERR x\r\n", "*0\r\n-ERR x\r\n"},
+		{"", ""},
+	}
+
+	for i, c := range table {
+		a, err := rdx.Canonicalize([]byte(c.a))
+		if err != nil {
+			t.Errorf("[%d] Canonicalize(%q) err = %v", i, c.a, err)
+			continue
+		}
+		b, err := rdx.Canonicalize([]byte(c.b))
+		if err != nil {
+			t.Errorf("[%d] Canonicalize(%q) err = %v", i, c.b, err)
+			continue
+		}
+		if string(a) != string(b) {
+			t.Errorf("[%d] Canonicalize(%q) = %q; Canonicalize(%q) = %q; want equal", i, c.a, a, c.b, b)
+		}
+		if string(b) != c.b {
+			t.Errorf("[%d] Canonicalize(%q) = %q; want unchanged", i, c.b, b)
+		}
+	}
+}
+
+func TestCanonicalize_errors(t *testing.T) {
+	for i, in := range []string{"+OK", "*2\r\n:1\r\n", "$5\r\nab"} {
+		if _, err := rdx.Canonicalize([]byte(in)); err != io.ErrUnexpectedEOF {
+			t.Errorf("[%d] Canonicalize(%q) err = %v; want %v", i, in, err, io.ErrUnexpectedEOF)
+		}
+	}
+	if _, err := rdx.Canonicalize([]byte("@\r\n")); err == nil {
+		t.Errorf("Canonicalize(invalid) err = nil; want error")
+	}
+}
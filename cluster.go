@@ -0,0 +1,53 @@
+package rdx
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// NumSlots is the number of hash slots in a Redis Cluster.
+const NumSlots = 16384
+
+// ParseRedirect parses a cluster redirect error of the form "MOVED <slot> <addr>" or
+// "ASK <slot> <addr>", returning the kind ("MOVED" or "ASK"), slot, and address. If e is not
+// a well-formed redirect, ok is false.
+//
+// The address is returned in the host:port form accepted by net.Dial, so IPv6 hosts, which
+// Redis sends without brackets (e.g., "::1:6381"), are returned in brackets ("[::1]:6381").
+// The host is empty if the server has no preferred endpoint, in which case the client should
+// use the host it sent the command to.
+func ParseRedirect(e Error) (kind string, slot int, addr string, ok bool) {
+	kind = e.Kind()
+	if kind != "MOVED" && kind != "ASK" {
+		return "", 0, "", false
+	}
+
+	fields := strings.Split(e.Message(), " ")
+	if len(fields) != 2 {
+		return "", 0, "", false
+	}
+
+	slot, err := strconv.Atoi(fields[0])
+	if err != nil || slot < 0 || slot >= NumSlots {
+		return "", 0, "", false
+	}
+
+	addr = fields[1]
+	i := strings.LastIndexByte(addr, ':')
+	if i < 0 {
+		return "", 0, "", false
+	}
+	host, port := addr[:i], addr[i+1:]
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return "", 0, "", false
+	}
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	if strings.ContainsAny(host, "[]") {
+		return "", 0, "", false
+	}
+
+	return kind, slot, net.JoinHostPort(host, port), true
+}
@@ -0,0 +1,45 @@
+package rdx_test
+
+import (
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestParseRedirect(t *testing.T) {
+	table := []struct {
+		err  rdx.Error
+		kind string
+		slot int
+		addr string
+		ok   bool
+	}{
+		{"MOVED 3999 127.0.0.1:6381", "MOVED", 3999, "127.0.0.1:6381", true},
+		{"ASK 0 redis-1.example.com:7000", "ASK", 0, "redis-1.example.com:7000", true},
+		{"MOVED 16383 ::1:6381", "MOVED", 16383, "[::1]:6381", true},
+		{"ASK 12 [fe80::1%eth0]:6381", "ASK", 12, "[fe80::1%eth0]:6381", true},
+		{"MOVED 3999 :6380", "MOVED", 3999, ":6380", true},
+
+		{"ERR 3999 127.0.0.1:6381", "", 0, "", false},
+		{"moved 3999 127.0.0.1:6381", "", 0, "", false},
+		{"MOVED", "", 0, "", false},
+		{"MOVED 3999", "", 0, "", false},
+		{"MOVED 3999 127.0.0.1:6381 extra", "", 0, "", false},
+		{"MOVED  3999 127.0.0.1:6381", "", 0, "", false},
+		{"MOVED -1 127.0.0.1:6381", "", 0, "", false},
+		{"MOVED 16384 127.0.0.1:6381", "", 0, "", false},
+		{"MOVED x 127.0.0.1:6381", "", 0, "", false},
+		{"MOVED 3999 127.0.0.1", "", 0, "", false},
+		{"MOVED 3999 127.0.0.1:", "", 0, "", false},
+		{"MOVED 3999 127.0.0.1:65536", "", 0, "", false},
+		{"MOVED 3999 [::1:6381", "", 0, "", false},
+	}
+
+	for i, c := range table {
+		kind, slot, addr, ok := rdx.ParseRedirect(c.err)
+		if kind != c.kind || slot != c.slot || addr != c.addr || ok != c.ok {
+			t.Errorf("[%d] ParseRedirect(%q) = %q, %d, %q, %t; want %q, %d, %q, %t",
+				i, c.err, kind, slot, addr, ok, c.kind, c.slot, c.addr, c.ok)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package rdx
+
+import (
+	"bytes"
+	"io"
+)
+
+var pingCommand = []byte("*1\r\n$4\r\nPING\r\n")
+
+// Ping writes a PING command to w. Its reply can be checked with MatchPong.
+func Ping(w io.Writer) error {
+	_, err := w.Write(pingCommand)
+	return err
+}
+
+// MatchPong reports whether m is a reply to a PING command without arguments: either a simple or
+// bulk string containing PONG.
+func MatchPong(m Msg) bool {
+	return IsA(m, TString) && m.String() == "PONG"
+}
+
+// WriteCommand writes a command to w as an array of bulk strings, one per argument in args. It
+// encodes args directly without constructing intermediate messages.
+func WriteCommand(w io.Writer, args ...string) (int, error) {
+	sz := 3 + intlen(int64(len(args)))
+	for _, arg := range args {
+		sz += 5 + intlen(int64(len(arg))) + len(arg)
+	}
+
+	buf, ok := w.(*bytes.Buffer)
+	if !ok {
+		buf = tempbuffer(sz)
+		defer putbuffer(buf)
+	}
+
+	start := buf.Len()
+	putint(buf, '*', int64(len(args)))
+	for _, arg := range args {
+		putint(buf, '$', int64(len(arg)))
+		buf.WriteString(arg)
+		buf.WriteString("\r\n")
+	}
+
+	if ok {
+		return buf.Len() - start, nil
+	}
+	n, err := buf.WriteTo(w)
+	return int(n), err
+}
+
+// WriteCommandBytes is the same as WriteCommand, but for binary arguments.
+func WriteCommandBytes(w io.Writer, args ...[]byte) (int, error) {
+	sz := 3 + intlen(int64(len(args)))
+	for _, arg := range args {
+		sz += 5 + intlen(int64(len(arg))) + len(arg)
+	}
+
+	buf, ok := w.(*bytes.Buffer)
+	if !ok {
+		buf = tempbuffer(sz)
+		defer putbuffer(buf)
+	}
+
+	start := buf.Len()
+	putint(buf, '*', int64(len(args)))
+	for _, arg := range args {
+		putint(buf, '$', int64(len(arg)))
+		buf.Write(arg)
+		buf.WriteString("\r\n")
+	}
+
+	if ok {
+		return buf.Len() - start, nil
+	}
+	n, err := buf.WriteTo(w)
+	return int(n), err
+}
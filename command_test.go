@@ -0,0 +1,106 @@
+package rdx_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestPing(t *testing.T) {
+	var buf bytes.Buffer
+	if err := rdx.Ping(&buf); err != nil {
+		t.Fatalf("Ping() err = %v", err)
+	}
+	if want := "*1\r\n$4\r\nPING\r\n"; buf.String() != want {
+		t.Fatalf("Ping() wrote %q; want %q", buf.String(), want)
+	}
+}
+
+func TestMatchPong(t *testing.T) {
+	table := []struct {
+		msg  string
+		want bool
+	}{
+		{"+PONG\r\n", true},
+		{"$4\r\nPONG\r\n", true},
+		{"+pong\r\n", false},
+		{"+PONG!\r\n", false},
+		{"-PONG\r\n", false},
+		{"*1\r\n+PONG\r\n", false},
+		{"$-1\r\n", false},
+	}
+
+	for i, c := range table {
+		msg, err := rdx.NewReader(strings.NewReader(c.msg)).Read()
+		if err != nil {
+			t.Fatalf("[%d] Read() err = %v", i, err)
+		}
+		if got := rdx.MatchPong(msg); got != c.want {
+			t.Errorf("[%d] MatchPong(%q) = %t; want %t", i, c.msg, got, c.want)
+		}
+	}
+
+	if !rdx.MatchPong(rdx.SimpleString("PONG")) || !rdx.MatchPong(rdx.BulkString("PONG")) {
+		t.Error("MatchPong() = false for encode-side PONG types; want true")
+	}
+	if rdx.MatchPong(nil) {
+		t.Error("MatchPong(nil) = true; want false")
+	}
+}
+
+func TestWriteCommand(t *testing.T) {
+	table := []struct {
+		args []string
+		want string
+	}{
+		{nil, "*0\r\n"},
+		{[]string{"PING"}, "*1\r\n$4\r\nPING\r\n"},
+		{[]string{"SET", "key", ""}, "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$0\r\n\r\n"},
+		{[]string{"SET", "k", "a\r\nb"}, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$4\r\na\r\nb\r\n"},
+	}
+
+	for i, c := range table {
+		var bargs [][]byte
+		for _, arg := range c.args {
+			bargs = append(bargs, []byte(arg))
+		}
+
+		for _, write := range []func(w io.Writer) (int, error){
+			func(w io.Writer) (int, error) { return rdx.WriteCommand(w, c.args...) },
+			func(w io.Writer) (int, error) { return rdx.WriteCommandBytes(w, bargs...) },
+		} {
+			// Write to a buffer with existing contents to check the returned length.
+			buf := bytes.NewBufferString("prefix")
+			if n, err := write(buf); err != nil || n != len(c.want) {
+				t.Errorf("[%d] WriteCommand() = %d, %v; want %d, nil", i, n, err, len(c.want))
+			} else if got := strings.TrimPrefix(buf.String(), "prefix"); got != c.want {
+				t.Errorf("[%d] WriteCommand() wrote %q; want %q", i, got, c.want)
+			}
+
+			var sb strings.Builder
+			if n, err := write(&sb); err != nil || n != len(c.want) {
+				t.Errorf("[%d] WriteCommand() = %d, %v; want %d, nil", i, n, err, len(c.want))
+			} else if sb.String() != c.want {
+				t.Errorf("[%d] WriteCommand() wrote %q; want %q", i, sb.String(), c.want)
+			}
+		}
+	}
+}
+
+func BenchmarkWriteCommand(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rdx.WriteCommand(ioutil.Discard, "SET", "key", "value")
+	}
+}
+
+func BenchmarkWriteArrayCommand(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rdx.Write(ioutil.Discard, rdx.Array{rdx.BulkString("SET"), rdx.BulkString("key"), rdx.BulkString("value")})
+	}
+}
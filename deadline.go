@@ -0,0 +1,228 @@
+package rdx
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// A readDeadliner is any reader that supports read deadlines, such as a net.Conn.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// A clock supplies the current time and sets read deadlines for a Reader. It exists so that
+// tests can drive the deadline-based features without depending on the real clock.
+type clock struct {
+	now         func() time.Time
+	setDeadline func(conn readDeadliner, t time.Time) error
+}
+
+// realClock is the clock used by Readers that don't have one set.
+var realClock = &clock{
+	now: time.Now,
+	setDeadline: func(conn readDeadliner, t time.Time) error {
+		return conn.SetReadDeadline(t)
+	},
+}
+
+// clk returns r's clock, defaulting to realClock.
+func (r *Reader) clk() *clock {
+	if r.clock == nil {
+		return realClock
+	}
+	return r.clock
+}
+
+// ErrTimeout is returned when a message is not read within a Reader's ReadTimeout. Like the
+// errors of expired net.Conn deadlines, it has a Timeout method that returns true.
+var ErrTimeout error = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "rdx: read timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// deadlines holds the read deadline state of a Reader's connection.
+type deadlines struct {
+	mu        sync.Mutex
+	cancelled bool      // true if a ReadContext call has been cancelled
+	base      time.Time // the deadline in effect outside of FirstByteTimeout
+	cur       time.Time // the deadline last set by setReadDeadline
+	msg       time.Time // the ReadTimeout deadline of the current message, if any
+}
+
+// aLongTimeAgo is a read deadline in the past, used to interrupt blocked reads.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// setReadDeadline sets the read deadline of the underlying connection. If the current
+// ReadContext call has been cancelled, the deadline is kept in the past so that a deadline set
+// while reading cannot undo the cancellation.
+func (r *Reader) setReadDeadline(t time.Time) error {
+	r.dl.mu.Lock()
+	defer r.dl.mu.Unlock()
+	r.dl.cur = t
+	if r.dl.cancelled {
+		t = aLongTimeAgo
+	}
+	return r.clk().setDeadline(r.conn, t)
+}
+
+// startTimeout starts the ReadTimeout of a new top-level message. If ReadTimeout is no longer
+// set, the deadline of the previous message is cleared.
+func (r *Reader) startTimeout() error {
+	if r.conn == nil {
+		return nil
+	} else if r.ReadTimeout > 0 {
+		r.dl.msg = r.clk().now().Add(r.ReadTimeout)
+		return nil
+	} else if r.dl.msg.IsZero() {
+		return nil
+	}
+	r.dl.msg = time.Time{}
+	return r.setReadDeadline(r.dl.cur)
+}
+
+// timeoutReader is the reader a Reader buffers when it reads from a connection. Before each
+// read, it sets the connection's read deadline to the current message's ReadTimeout deadline,
+// unless an earlier deadline is in effect, and reports reads that exceed it as ErrTimeout.
+type timeoutReader struct {
+	r    *Reader
+	conn io.Reader
+}
+
+func (t *timeoutReader) Read(p []byte) (int, error) {
+	r := t.r
+	if r.dl.msg.IsZero() {
+		return t.conn.Read(p)
+	}
+
+	r.dl.mu.Lock()
+	deadline := r.dl.msg
+	if cur := r.dl.cur; !cur.IsZero() && cur.Before(deadline) {
+		deadline = cur
+	}
+	if r.dl.cancelled {
+		deadline = aLongTimeAgo
+	}
+	err := r.clk().setDeadline(r.conn, deadline)
+	r.dl.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := t.conn.Read(p)
+	if err != nil && isTimeout(err) && !r.clk().now().Before(r.dl.msg) {
+		err = ErrTimeout
+	}
+	return n, err
+}
+
+// awaitFirstByte waits for the first byte of a message to become available if FirstByteTimeout
+// is set, restoring the previous read deadline once it arrives.
+func (r *Reader) awaitFirstByte() (err error) {
+	if r.FirstByteTimeout <= 0 || r.conn == nil {
+		return nil
+	}
+
+	if _, ok := r.src.r.(*prependReader); ok {
+		// Prepended bytes are available immediately.
+		return nil
+	}
+
+	br, buffered := r.src.r.(*bufio.Reader)
+	bs, scanner := r.src.r.(io.ByteScanner)
+	if buffered && br.Buffered() > 0 {
+		return nil
+	} else if !buffered && !scanner {
+		return nil
+	}
+
+	deadline := r.clk().now().Add(r.FirstByteTimeout)
+	if base := r.dl.base; !base.IsZero() && base.Before(deadline) {
+		deadline = base
+	}
+	if err = r.setReadDeadline(deadline); err != nil {
+		return err
+	}
+	defer func() {
+		if derr := r.setReadDeadline(r.dl.base); err == nil {
+			err = derr
+		}
+	}()
+
+	if buffered {
+		_, err = br.Peek(1)
+	} else if _, err = bs.ReadByte(); err == nil {
+		err = bs.UnreadByte()
+	}
+	return err
+}
+
+// ReadContext reads the next message, returning ctx.Err() if ctx is done before the message is
+// read. Cancellation requires the reader passed to NewReader or Reset to support read deadlines
+// (e.g., a net.Conn): ctx's deadline is used as the read deadline, and the read is interrupted
+// when ctx is cancelled. If the reader doesn't support read deadlines, ctx is only checked
+// before reading.
+//
+// If ctx is done before any byte of the message has been read, no data is lost and the Reader
+// can continue to be used. If ctx is done partway through a message, the part of the message
+// that was read is discarded along with any position in the stream, and the Reader should be
+// closed or Reset rather than read from again.
+func (r *Reader) ReadContext(ctx context.Context) (Msg, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if r.conn == nil || ctx.Done() == nil {
+		return r.Read()
+	}
+
+	r.dl.base, _ = ctx.Deadline()
+	if err := r.setReadDeadline(r.dl.base); err != nil {
+		return nil, err
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			r.dl.mu.Lock()
+			r.dl.cancelled = true
+			r.clk().setDeadline(r.conn, aLongTimeAgo)
+			r.dl.mu.Unlock()
+		case <-stop:
+		}
+	}()
+
+	msg, err := r.Read()
+	close(stop)
+	<-done
+
+	r.dl.mu.Lock()
+	r.dl.cancelled = false
+	r.dl.mu.Unlock()
+	r.dl.base = time.Time{}
+	if derr := r.setReadDeadline(r.dl.base); err == nil {
+		err = derr
+	}
+
+	if err != nil {
+		if cerr := ctx.Err(); cerr != nil {
+			return nil, cerr
+		} else if d, ok := ctx.Deadline(); ok && isTimeout(err) && !r.clk().now().Before(d) {
+			// The read deadline can expire before ctx notices its own deadline.
+			return nil, context.DeadlineExceeded
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// isTimeout reports whether err is a timeout error, such as from an expired read deadline.
+func isTimeout(err error) bool {
+	te, ok := err.(interface{ Timeout() bool })
+	return ok && te.Timeout()
+}
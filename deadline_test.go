@@ -0,0 +1,267 @@
+package rdx_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.spiff.io/rdx"
+)
+
+// fakeClock is a manually advanced clock for testing deadlines.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+// fakeRead is a read that completes delay after the previous one.
+type fakeRead struct {
+	delay time.Duration
+	data  string
+}
+
+// fakeConn is a connection driven by a fakeClock. A read whose delay passes the read deadline
+// advances the clock to the deadline and fails with os.ErrDeadlineExceeded.
+type fakeConn struct {
+	clock     *fakeClock
+	reads     []fakeRead
+	deadline  time.Time
+	deadlines []time.Time // every deadline set, in order
+}
+
+func (c *fakeConn) SetReadDeadline(t time.Time) error {
+	c.deadline = t
+	c.deadlines = append(c.deadlines, t)
+	return nil
+}
+
+func (c *fakeConn) Read(p []byte) (int, error) {
+	if len(c.reads) == 0 {
+		return 0, io.EOF
+	}
+	rd := &c.reads[0]
+	at := c.clock.t.Add(rd.delay)
+	if !c.deadline.IsZero() && at.After(c.deadline) {
+		rd.delay = at.Sub(c.deadline)
+		c.clock.t = c.deadline
+		return 0, os.ErrDeadlineExceeded
+	}
+	c.clock.t, rd.delay = at, 0
+	n := copy(p, rd.data)
+	if rd.data = rd.data[n:]; rd.data == "" {
+		c.reads = c.reads[1:]
+	}
+	return n, nil
+}
+
+func newFakeConnReader(reads ...fakeRead) (*rdx.Reader, *fakeConn) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	conn := &fakeConn{clock: clock, reads: reads}
+	r := rdx.NewReader(conn)
+	r.SetClock(clock.Now, nil)
+	return r, conn
+}
+
+func TestReader_FirstByteTimeout(t *testing.T) {
+	const timeout = time.Second
+
+	t.Run("IdleTimeout", func(t *testing.T) {
+		r, conn := newFakeConnReader(fakeRead{timeout * 5, ":1\r\n"})
+		r.FirstByteTimeout = timeout
+
+		start := conn.clock.Now()
+		_, err := r.Read()
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			t.Fatalf("Read() err = %v; want timeout", err)
+		}
+		if got, want := conn.clock.Now().Sub(start), timeout; got != want {
+			t.Fatalf("Read() timed out after %v; want %v", got, want)
+		}
+
+		// The reader remains usable once the message arrives.
+		r.FirstByteTimeout = timeout * 10
+		if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+			t.Fatalf("Read() = %v, %v; want 1, nil", msg, err)
+		}
+	})
+
+	t.Run("SlowBody", func(t *testing.T) {
+		r, conn := newFakeConnReader(
+			fakeRead{0, "$5\r\n"},
+			fakeRead{timeout * 5, "hello\r\n"},
+		)
+		r.FirstByteTimeout = timeout
+
+		start := conn.clock.Now()
+		if msg, err := r.Read(); err != nil || msg.String() != "hello" {
+			t.Fatalf("Read() = %v, %v; want hello, nil", msg, err)
+		}
+
+		// The first-byte deadline is cleared once the first byte arrives.
+		want := []time.Time{start.Add(timeout), {}}
+		if !reflect.DeepEqual(conn.deadlines, want) {
+			t.Fatalf("deadlines = %v; want %v", conn.deadlines, want)
+		}
+	})
+}
+
+func TestReader_ReadContext(t *testing.T) {
+	t.Run("Cancel", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		r := rdx.NewReader(server)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		if msg, err := r.ReadContext(ctx); err != context.Canceled {
+			t.Fatalf("ReadContext() = %v, %v; want nil, %v", msg, err, context.Canceled)
+		}
+
+		// Nothing was read, so the reader is still usable.
+		go client.Write([]byte(":1\r\n"))
+		if msg, err := r.ReadContext(context.Background()); err != nil || msg != rdx.Int(1) {
+			t.Fatalf("ReadContext() = %v, %v; want 1, nil", msg, err)
+		}
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		r := rdx.NewReader(server)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		go client.Write([]byte("$5\r\nhel"))
+		if msg, err := r.ReadContext(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("ReadContext() = %v, %v; want nil, %v", msg, err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("FirstByteTimeout", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		// The context deadline still applies after the first byte arrives.
+		r := rdx.NewReader(server)
+		r.FirstByteTimeout = time.Second
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		go client.Write([]byte("$5\r\nhel"))
+		if msg, err := r.ReadContext(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("ReadContext() = %v, %v; want nil, %v", msg, err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("NoDeadlines", func(t *testing.T) {
+		r := rdx.NewReader(strings.NewReader(":1\r\n:2\r\n"))
+		if msg, err := r.ReadContext(context.Background()); err != nil || msg != rdx.Int(1) {
+			t.Fatalf("ReadContext() = %v, %v; want 1, nil", msg, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if msg, err := r.ReadContext(ctx); err != context.Canceled {
+			t.Fatalf("ReadContext() = %v, %v; want nil, %v", msg, err, context.Canceled)
+		}
+	})
+}
+
+func TestReader_ReadTimeout(t *testing.T) {
+	const timeout = time.Second
+
+	t.Run("SlowLoris", func(t *testing.T) {
+		reads := []fakeRead{{0, "$5\r\n"}}
+		for _, c := range "hello\r\n" {
+			reads = append(reads, fakeRead{timeout / 4, string(c)})
+		}
+		r, conn := newFakeConnReader(reads...)
+		r.ReadTimeout = timeout
+
+		start := conn.clock.Now()
+		if msg, err := r.Read(); err != rdx.ErrTimeout {
+			t.Fatalf("Read() = %v, %v; want nil, %v", msg, err, rdx.ErrTimeout)
+		}
+		if got := conn.clock.Now().Sub(start); got != timeout {
+			t.Fatalf("Read() timed out after %v; want %v", got, timeout)
+		}
+		for i, d := range conn.deadlines {
+			if want := start.Add(timeout); !d.Equal(want) {
+				t.Fatalf("deadlines[%d] = %v; want %v", i, d, want)
+			}
+		}
+	})
+
+	t.Run("Idle", func(t *testing.T) {
+		r, _ := newFakeConnReader(fakeRead{timeout * 2, ":1\r\n"})
+		r.ReadTimeout = timeout
+
+		_, err := r.Read()
+		if ne, ok := err.(net.Error); err != rdx.ErrTimeout || !ok || !ne.Timeout() {
+			t.Fatalf("Read() err = %v; want %v", err, rdx.ErrTimeout)
+		}
+
+		// Nothing was read, so the reader is still usable, and each message gets its own
+		// timeout.
+		if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+			t.Fatalf("Read() = %v, %v; want 1, nil", msg, err)
+		}
+	})
+
+	t.Run("PerMessage", func(t *testing.T) {
+		r, _ := newFakeConnReader(
+			fakeRead{timeout / 2, ":1\r\n"},
+			fakeRead{timeout / 2, ":2\r\n"},
+			fakeRead{timeout / 2, ":3\r\n"},
+		)
+		r.ReadTimeout = timeout
+
+		for i := rdx.Int(1); i <= 3; i++ {
+			if msg, err := r.Read(); err != nil || msg != i {
+				t.Fatalf("Read() = %v, %v; want %v, nil", msg, err, i)
+			}
+		}
+	})
+
+	t.Run("FirstByteTimeout", func(t *testing.T) {
+		// The earlier first-byte deadline is reported as the underlying error.
+		r, conn := newFakeConnReader(fakeRead{timeout, ":1\r\n"})
+		r.ReadTimeout = timeout
+		r.FirstByteTimeout = timeout / 2
+
+		start := conn.clock.Now()
+		_, err := r.Read()
+		if ne, ok := err.(net.Error); err == rdx.ErrTimeout || !ok || !ne.Timeout() {
+			t.Fatalf("Read() err = %v; want first-byte timeout", err)
+		}
+		if got, want := conn.clock.Now().Sub(start), timeout/2; got != want {
+			t.Fatalf("Read() timed out after %v; want %v", got, want)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		r, conn := newFakeConnReader(fakeRead{0, ":1\r\n"}, fakeRead{timeout * 2, ":2\r\n"})
+		r.ReadTimeout = timeout
+		if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+			t.Fatalf("Read() = %v, %v; want 1, nil", msg, err)
+		}
+
+		// Clearing ReadTimeout clears the deadline left by the last message.
+		r.ReadTimeout = 0
+		if msg, err := r.Read(); err != nil || msg != rdx.Int(2) {
+			t.Fatalf("Read() = %v, %v; want 2, nil", msg, err)
+		}
+		if last := conn.deadlines[len(conn.deadlines)-1]; !last.IsZero() {
+			t.Fatalf("last deadline = %v; want none", last)
+		}
+	})
+}
@@ -6,16 +6,42 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
 )
 
 var (
-	ErrMissingPrefix = errors.New("rdx: missing type prefix")
-	ErrMissingCRLF   = errors.New("rdx: missing CRLF sequence")
-	ErrIntRange      = errors.New("rdx: integer out of range of int64")
-	ErrBadLength     = errors.New("rdx: invalid length")
-	ErrInvalidInt    = errors.New("rdx: malformed integer / length")
-	ErrEmptyInt      = errors.New("rdx: empty integer / length")
-	ErrInvalidLength = errors.New("rdx: invalid length")
+	ErrMissingPrefix   = errors.New("rdx: missing type prefix")
+	ErrMissingCRLF     = errors.New("rdx: missing CRLF sequence")
+	ErrIntRange        = errors.New("rdx: integer out of range of int64")
+	ErrInvalidInt      = errors.New("rdx: malformed integer")
+	ErrEmptyInt        = errors.New("rdx: empty integer / length")
+	ErrInvalidLength   = errors.New("rdx: malformed length")
+	ErrNegativeLength  = errors.New("rdx: negative length other than -1")
+	ErrOddMapLength    = errors.New("rdx: map key has no value")
+	ErrInvalidDouble   = errors.New("rdx: malformed double")
+	ErrEmptyDouble     = errors.New("rdx: empty double")
+	ErrDoubleRange     = errors.New("rdx: double out of range of float64")
+	ErrInvalidNull     = errors.New("rdx: null has trailing data")
+	ErrInvalidBigNum   = errors.New("rdx: malformed big number")
+	ErrInvalidBool     = errors.New("rdx: malformed boolean")
+	ErrBulkTooLarge    = errors.New("rdx: bulk string exceeds maximum size")
+	ErrNotCommand      = errors.New("rdx: message is not a command")
+	ErrArrayTooLong    = errors.New("rdx: array exceeds maximum length")
+	ErrSimpleTooLong   = errors.New("rdx: simple string exceeds maximum length")
+	ErrErrorTooLong    = errors.New("rdx: error exceeds maximum length")
+	ErrMessageTooLarge = errors.New("rdx: message exceeds maximum size")
+	ErrInvalidChunk    = errors.New("rdx: malformed streamed string chunk")
+
+	ErrMaxDepthExceeded = errors.New("rdx: message exceeds maximum nesting depth")
+	ErrStreamNotDrained = errors.New("rdx: bulk string stream was not drained")
+
+	// ErrBadLength is the same error as ErrInvalidLength.
+	//
+	// Deprecated: Use ErrInvalidLength.
+	ErrBadLength = ErrInvalidLength
 )
 
 type InvalidPrefixError byte
@@ -34,18 +60,195 @@ type bytesReader interface {
 }
 
 type Reader struct {
+	src  *source       // shared with Readers from ReadCommandName
+	buf  *bufio.Reader // buffer for readers that aren't bytesReaders, kept for Reset
+	conn readDeadliner // the reader passed to Reset, if it supports read deadlines
+
+	dl    *deadlines // deadline state of conn; shared with Readers from ReadCommandName
+	clock *clock     // clock used for deadlines; nil uses the real clock
+	nread *int64     // bytes consumed; shared with Readers from ReadCommandName
+	start int64      // value of *nread at the start of the current top-level message
+
+	// MaxBulkSize, if greater than zero, is the maximum length of a bulk string. Bulk strings
+	// with a declared length greater than MaxBulkSize are rejected with ErrBulkTooLarge before
+	// allocating memory for them.
+	MaxBulkSize int
+
+	// MaxArrayLen, if greater than zero, is the maximum length of an array, set, or push
+	// message, or the maximum number of pairs in a map. Aggregates with a declared length
+	// greater than MaxArrayLen are rejected with ErrArrayTooLong before allocating memory for
+	// them.
+	MaxArrayLen int
+
+	// MaxDepth, if greater than zero, is the maximum nesting depth of aggregate messages
+	// (arrays, maps, sets, and pushes). A top-level aggregate has a depth of 1. Messages nested
+	// deeper than MaxDepth are rejected with ErrMaxDepthExceeded.
+	MaxDepth int
+
+	// MaxSimpleLen and MaxErrorLen, if greater than zero, are the maximum content lengths of
+	// simple strings and errors, respectively. Because these messages have no declared length,
+	// the limits are checked once the line has been read, and lines exceeding them are rejected
+	// with ErrSimpleTooLong or ErrErrorTooLong.
+	MaxSimpleLen int
+	MaxErrorLen  int
+
+	// MaxMessageSize, if greater than zero, is the maximum encoded size in bytes of a single
+	// top-level message, including all of its nested messages. It composes with the other
+	// limits: a message must satisfy all of them. Bulk strings are checked against the limit
+	// using their declared length before their payload is read, and messages exceeding it are
+	// rejected with ErrMessageTooLarge. The arguments read from a Reader returned by
+	// ReadCommandName count towards the size of the command. The payloads of streamed bulk
+	// strings (see ReadStream) do not count.
+	MaxMessageSize int
+
+	// FirstByteTimeout, if greater than zero, is how long Read waits for the first byte of a
+	// message to arrive. Once the first byte has been read, the rest of the message is read
+	// without a deadline. FirstByteTimeout requires that the reader passed to NewReader or
+	// Reset supports read deadlines (e.g., a net.Conn). If it doesn't, or it is a bytesReader
+	// (such as a bufio.Reader) that hides the underlying connection, FirstByteTimeout has no
+	// effect. When the timeout expires, Read returns the error from the underlying reader.
+	FirstByteTimeout time.Duration
+
+	// ReadTimeout, if greater than zero, is how long Read may take to read a message in full,
+	// measured from the call to Read (or PeekType). Before each read from the underlying
+	// reader, its read deadline is set to the end of the timeout, so a peer that sends a
+	// message slowly, such as one byte at a time, cannot hold up Read indefinitely. When the
+	// timeout expires, Read returns ErrTimeout. Earlier deadlines, such as those of
+	// FirstByteTimeout and ReadContext, take precedence. For a command read with
+	// ReadCommandName, the timeout covers reading all of its arguments, and for a bulk string
+	// read with ReadStream, it covers reading the body.
+	//
+	// If the timeout expires before any byte of the message has been read, no data is lost and
+	// the Reader can continue to be used. If it expires partway through a message, the part of
+	// the message that was read is discarded, and the Reader should be closed or Reset rather
+	// than read from again. Like FirstByteTimeout, ReadTimeout requires a reader that supports
+	// read deadlines and that isn't a bytesReader.
+	ReadTimeout time.Duration
+
+	// ArrayRing, if greater than zero, is the number of reusable buffers used to hold the
+	// elements of arrays, sets, and pushes. Each top-level message read takes the next free
+	// buffer in the ring for all of its arrays. A buffer is free until it is used and again
+	// once ReleaseLast is called after the message that used it, or ReleaseAll is called. If no
+	// buffer is free, the message's arrays are allocated as usual. This allows a pipeline of
+	// array replies to be read without allocating their elements, but arrays read through the
+	// ring alias its buffers, so a released message must not be used again.
+	ArrayRing int
+
+	// AllowInline, if true, enables reading inline commands: top-level lines that don't begin
+	// with a message type prefix, such as "SET foo bar\r\n" typed into a telnet session.
+	// Inline commands are split into arguments on whitespace and returned as an Array of
+	// strings. As in Redis, an argument containing whitespace may be enclosed in double
+	// quotes, which support backslash escapes such as \n and \xHH, or in single quotes.
+	// Unbalanced quotes are rejected with ErrUnbalancedQuotes. Blank lines between messages
+	// are skipped. ReadCommandName also accepts inline commands.
+	AllowInline bool
+
+	// PreserveStringTypes, if true, makes Read return simple strings as SimpleString instead of
+	// String, so that they can be told apart from bulk strings, which are always read as
+	// String. This is intended for tests that check which form a peer sent.
+	PreserveStringTypes bool
+
+	// Arena, if non-nil, is used to allocate the payloads of bulk strings. See Arena for the
+	// aliasing rules of strings allocated from it.
+	Arena *Arena
+
+	proto  Protocol // set by SetProtocol
+	prefix byte
+	depth  int
+	stream *bulkStream // the undrained stream returned by ReadStream, if any
+
+	prefixes map[byte]PrefixFunc // set by RegisterPrefix
+	inCustom int                 // greater than zero while a PrefixFunc is reading a message
+
+	unread  Msg   // the message pushed back by UnreadMsg, if any
+	msg     Msg   // the message read by the last call to Scan
+	scanErr error // the error that stopped Scan, if any
+
+	reuse *reuse // storage of the message passed to ReadInto
+
+	ring    []*arraySlab // the ArrayRing buffers
+	ringPos int          // index of the next ring buffer to try
+	slab    *arraySlab   // the ring buffer available to the current message, if any
+	last    *arraySlab   // the ring buffer used by the last message, if any
+
+	// cmd is true for Readers returned by ReadCommandName, in which case args is the number of
+	// command arguments left to read.
+	cmd  bool
+	args int
+}
+
+// source is the underlying reader of a Reader, along with the state that follows its position
+// in the stream. A Reader returned by ReadCommandName shares its source with the Reader it was
+// read from, so that changes to either, such as unwrapping a drained Prepend, apply to both.
+type source struct {
 	r bytesReader
+
+	// nilCRLF is true after reading a nil bulk string, which may be followed by an empty line.
+	nilCRLF bool
 }
 
 func NewReader(r io.Reader) *Reader {
-	var br bytesReader
-	if ir, ok := r.(bytesReader); ok {
-		br = ir
+	rd := new(Reader)
+	rd.Reset(r)
+	return rd
+}
+
+// NewReaderSize returns a new Reader reading from r. If r is not a bytesReader, it is wrapped in
+// a bufio.Reader with a buffer of at least size bytes, as by bufio.NewReaderSize, which enforces
+// a minimum size of 16 bytes. Because messages are not required to fit in the buffer, a small
+// buffer reduces the memory held by idle connections at the cost of more reads, while a large
+// buffer suits streams of large bulk strings. If r is already a bytesReader, such as a
+// bufio.Reader, it is used as-is and size is ignored. Later calls to Reset keep the buffer.
+func NewReaderSize(r io.Reader, size int) *Reader {
+	rd := new(Reader)
+	if _, ok := r.(bytesReader); !ok {
+		rd.buf = bufio.NewReaderSize(nil, size)
+	}
+	rd.Reset(r)
+	return rd
+}
+
+// Reset discards any state held by the Reader and switches it to reading from r. If r is not a
+// bytesReader, it is wrapped in a bufio.Reader, reusing the Reader's existing bufio.Reader if it
+// has one. Reader options, such as MaxBulkSize and Arena, are left unchanged.
+func (r *Reader) Reset(rd io.Reader) {
+	if r.src == nil {
+		r.src = new(source)
+	}
+	r.conn, _ = rd.(readDeadliner)
+	if r.conn != nil {
+		r.dl = new(deadlines)
 	} else {
-		br = bufio.NewReader(r)
+		r.dl = nil
 	}
 
-	return &Reader{r: br}
+	if br, ok := rd.(bytesReader); ok {
+		r.src.r = br
+	} else {
+		if r.conn != nil {
+			rd = &timeoutReader{r: r, conn: rd}
+		}
+		if r.buf != nil {
+			r.buf.Reset(rd)
+		} else {
+			r.buf = bufio.NewReader(rd)
+		}
+		r.src.r = r.buf
+	}
+
+	r.prefix = 0
+	r.depth = 0
+	r.stream = nil
+	r.cmd, r.args = false, 0
+	r.slab, r.last = nil, nil
+	r.msg, r.scanErr = nil, nil
+	r.unread = nil
+	r.src.nilCRLF = false
+	if r.nread == nil {
+		r.nread = new(int64)
+	} else {
+		*r.nread = 0
+	}
 }
 
 func parseInt(b []byte) (n int64, err error) {
@@ -54,7 +257,12 @@ func parseInt(b []byte) (n int64, err error) {
 		off       = 0
 	)
 
-	if b[0] == '-' {
+	if len(b) == 0 {
+		return 0, ErrEmptyInt
+	} else if b[0] == '-' {
+		if len(b) == 1 {
+			return 0, ErrInvalidInt
+		}
 		off++
 		sc = -1
 	}
@@ -85,41 +293,254 @@ func (r *Reader) readInt(head []byte) (Int, error) {
 	return Int(n), err
 }
 
-func (r *Reader) readBulkString(head []byte) (Msg, error) {
+// maxBulkLen is the longest bulk string that can be allocated, regardless of MaxBulkSize.
+// Longer lengths, such as those near math.MaxInt64, would overflow an int once the length of
+// the trailing CRLF is added, or exceed the largest allocation the runtime supports.
+const maxBulkLen = min(math.MaxInt, 1<<47) - 2
+
+// bulkLength returns the declared length of a bulk string from its header. A length of -1
+// indicates a nil bulk string.
+func (r *Reader) bulkLength(head []byte) (Int, error) {
 	length, err := r.readInt(head)
 	if err != nil {
 		if err == ErrInvalidInt {
 			err = ErrInvalidLength
 		}
+		return 0, err
+	} else if length < -1 {
+		return 0, ErrNegativeLength
+	} else if length > maxBulkLen {
+		return 0, ErrBulkTooLarge
+	} else if length == -1 {
+		r.src.nilCRLF = true
+	}
+	return length, nil
+}
+
+// skipNilCRLF consumes the empty line that some older implementations write after a nil bulk
+// string ("$-1\r\n\r\n"), if the last message read was a nil bulk string and the next line is
+// empty. It reports whether the line was consumed. A real message never begins with an empty
+// line, so this doesn't change how well-formed streams are read.
+func (r *Reader) skipNilCRLF() (bool, error) {
+	if !r.src.nilCRLF {
+		return false, nil
+	}
+	r.src.nilCRLF = false
+
+	if c, err := r.peekByte(); err != nil || c != '\r' {
+		// Errors are left for the next read to report.
+		return false, nil
+	}
+	var tail [2]byte
+	if err := r.readFull(tail[:]); err != nil {
+		return false, err
+	} else if tail[1] != '\n' {
+		return false, ErrMissingCRLF
+	}
+	return true, nil
+}
+
+func (r *Reader) readBulkString(head []byte) (Msg, error) {
+	if r.isStreamed(head) {
+		s, err := r.readStreamedString()
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	length, err := r.bulkLength(head)
+	if err != nil {
 		return nil, err
 	}
 
 	if length == -1 {
 		return Nil, nil
-	} else if length < 0 {
-		return nil, ErrInvalidLength
+	} else if r.MaxBulkSize > 0 && length > Int(r.MaxBulkSize) {
+		return nil, ErrBulkTooLarge
+	} else if err := r.checkSize(int64(length) + 2); err != nil {
+		return nil, err
+	}
+
+	if r.Arena != nil {
+		return r.readArenaString(int(length))
+	} else if buf := r.reuse.str(int(length)); buf != nil {
+		return r.readStringInto(buf)
 	}
 
 	buf := make([]byte, length+2)
-	r.r.Read(buf)
-	if !bytes.HasSuffix(buf, crlf) {
+	if err := r.readFull(buf); err != nil {
+		return nil, err
+	} else if !bytes.HasSuffix(buf, crlf) {
 		return nil, ErrMissingCRLF
 	}
 
 	if length == 0 {
-		return String(nil), nil
+		return String{}, nil
 	}
 
 	sep := len(buf) - 2
 	return String(buf[:sep:sep]), nil
 }
 
+// readStreamedString reads the chunks of a RESP3 streamed string, which follow a "$?" header
+// as ";<length>" lines and their payloads up to a chunk of length zero, and returns their
+// concatenation. MaxBulkSize limits the length of the whole string.
+func (r *Reader) readStreamedString() (String, error) {
+	var buf []byte
+	for {
+		head, err := r.readHead()
+		if err != nil {
+			return nil, err
+		} else if head[0] != ';' {
+			return nil, ErrInvalidChunk
+		}
+
+		length, err := r.readInt(head)
+		if err != nil {
+			if err == ErrInvalidInt {
+				err = ErrInvalidLength
+			}
+			return nil, err
+		} else if length < 0 {
+			return nil, ErrInvalidChunk
+		} else if length == 0 && buf == nil {
+			return String{}, nil
+		} else if length == 0 {
+			return String(buf[:len(buf):len(buf)]), nil
+		} else if length > Int(maxBulkLen-len(buf)) {
+			return nil, ErrBulkTooLarge
+		} else if r.MaxBulkSize > 0 && length > Int(r.MaxBulkSize-len(buf)) {
+			return nil, ErrBulkTooLarge
+		} else if err := r.checkSize(int64(length) + 2); err != nil {
+			return nil, err
+		}
+
+		n := len(buf)
+		buf = append(buf, make([]byte, length+2)...)
+		if err := r.readFull(buf[n:]); err != nil {
+			return nil, err
+		} else if !bytes.HasSuffix(buf, crlf) {
+			return nil, ErrMissingCRLF
+		}
+		buf = buf[:len(buf)-2]
+	}
+}
+
+// readFull reads exactly len(buf) bytes of a message body. Because the body's header has
+// already been read, io.EOF is reported as io.ErrUnexpectedEOF.
+func (r *Reader) readFull(buf []byte) error {
+	n, err := io.ReadFull(r.src.r, buf)
+	*r.nread += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (r *Reader) readArenaString(length int) (Msg, error) {
+	buf := []byte{}
+	if length > 0 {
+		buf = r.Arena.alloc(length)
+	}
+	return r.readStringInto(buf)
+}
+
+// readStringInto reads a bulk string payload of len(buf) bytes into buf, followed by its
+// trailing CRLF.
+func (r *Reader) readStringInto(buf []byte) (Msg, error) {
+	if err := r.readFull(buf); err != nil {
+		return nil, err
+	}
+
+	var tail [2]byte
+	if err := r.readFull(tail[:]); err != nil {
+		return nil, err
+	} else if tail[0] != '\r' || tail[1] != '\n' {
+		return nil, ErrMissingCRLF
+	}
+
+	return String(buf), nil
+}
+
+// readDouble parses a RESP3 double. The non-finite values are only accepted in their spec
+// spellings of inf, -inf, and nan, ignoring case. Finite values must be decimal and may use
+// an exponent; values that overflow a float64 return ErrDoubleRange.
+func (r *Reader) readDouble(head []byte) (Msg, error) {
+	body := head[1 : len(head)-2]
+	switch {
+	case len(body) == 0:
+		return nil, ErrEmptyDouble
+	case bytes.EqualFold(body, []byte("inf")):
+		return Double(math.Inf(1)), nil
+	case bytes.EqualFold(body, []byte("-inf")):
+		return Double(math.Inf(-1)), nil
+	case bytes.EqualFold(body, []byte("nan")):
+		return Double(math.NaN()), nil
+	}
+
+	// Reject anything ParseFloat accepts beyond the RESP3 grammar, such as hex floats,
+	// underscores, or other spellings of infinity (e.g., "+inf" or "Infinity").
+	for _, c := range body {
+		if !isDoubleByte(c) {
+			return nil, ErrInvalidDouble
+		}
+	}
+	f, err := strconv.ParseFloat(string(body), 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, ErrDoubleRange
+	} else if err != nil {
+		return nil, ErrInvalidDouble
+	}
+	return Double(f), nil
+}
+
+func isDoubleByte(c byte) bool {
+	return '0' <= c && c <= '9' || c == '.' || c == '-' || c == '+' || c == 'e' || c == 'E'
+}
+
+func (r *Reader) readBigNumber(head []byte) (Msg, error) {
+	n := len(head) - 2
+	i, ok := new(big.Int).SetString(string(head[1:n]), 10)
+	if !ok {
+		return nil, ErrInvalidBigNum
+	}
+	return BigNumber{i}, nil
+}
+
+func (r *Reader) readBool(head []byte) (Msg, error) {
+	if len(head) == 4 {
+		switch head[1] {
+		case 't':
+			return Bool(true), nil
+		case 'f':
+			return Bool(false), nil
+		}
+	}
+	return nil, ErrInvalidBool
+}
+
 func (r *Reader) readSimpleString(head []byte) (String, error) {
 	n := len(head) - 2
+	if r.MaxSimpleLen > 0 && n-1 > r.MaxSimpleLen {
+		return nil, ErrSimpleTooLong
+	} else if bytes.IndexByte(head[1:n], '\r') >= 0 {
+		// A bare CR can't be encoded in a simple string. (A bare LF ends the line early and
+		// is caught by readHead.)
+		return nil, ErrInvalidSimpleStr
+	}
 	return String(head[1:n:n]), nil
 }
 
 func (r *Reader) readArray(head []byte) (Msg, error) {
+	if r.isStreamed(head) {
+		ary, err := r.readStreamed(false)
+		if err != nil {
+			return nil, err
+		}
+		return Array(ary), nil
+	}
+
 	length, err := r.readInt(head)
 	if err != nil {
 		if err == ErrInvalidInt {
@@ -131,42 +552,488 @@ func (r *Reader) readArray(head []byte) (Msg, error) {
 	if length == -1 {
 		return Nil, nil
 	} else if length < 0 {
-		return nil, ErrInvalidLength
-	} else if length == 0 {
+		return nil, ErrNegativeLength
+	}
+
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+
+	if length == 0 {
 		return Array(nil), nil
+	} else if r.MaxArrayLen > 0 && length > Int(r.MaxArrayLen) {
+		return nil, ErrArrayTooLong
 	}
 
-	ary := make([]Msg, length)
-	for i := range ary {
-		ary[i], err = r.Read()
+	ary := r.allocArray(length)
+	if ary == nil {
+		ary = r.reuse.array(int(length))
+	}
+	if ary != nil {
+		for i := range ary {
+			if ary[i], err = r.read(); err != nil {
+				return nil, err
+			}
+		}
+		return Array(ary), nil
+	}
+
+	ary = make([]Msg, 0, preallocLen(length))
+	for i := Int(0); i < length; i++ {
+		msg, err := r.read()
 		if err != nil {
 			return nil, err
 		}
+		ary = append(ary, msg)
 	}
 
 	return Array(ary), nil
 }
 
+// enter increments the nesting depth of the reader when reading an aggregate message. It
+// returns ErrMaxDepthExceeded if this would exceed MaxDepth. Every successful call to enter
+// must be followed by a call to leave.
+func (r *Reader) enter() error {
+	if r.MaxDepth > 0 && r.depth >= r.MaxDepth {
+		return ErrMaxDepthExceeded
+	}
+	r.depth++
+	return nil
+}
+
+func (r *Reader) leave() {
+	r.depth--
+}
+
+// maxPrealloc is the maximum number of elements allocated up front for an aggregate, since
+// declared lengths aren't trusted.
+const maxPrealloc = 1024
+
+// preallocLen returns the initial capacity to allocate for an aggregate of the given declared
+// length. Large aggregates are grown as their elements arrive instead of trusting the declared
+// length up front.
+func preallocLen(length Int) int {
+	if length > maxPrealloc {
+		return maxPrealloc
+	}
+	return int(length)
+}
+
+func (r *Reader) readSet(head []byte) (Msg, error) {
+	msg, err := r.readArray(head)
+	if ary, ok := msg.(Array); ok {
+		return Set(ary), err
+	}
+	return msg, err
+}
+
+func (r *Reader) readPush(head []byte) (Msg, error) {
+	msg, err := r.readArray(head)
+	if ary, ok := msg.(Array); ok {
+		return Push(ary), err
+	}
+	return msg, err
+}
+
+func (r *Reader) readMap(head []byte) (Msg, error) {
+	if r.isStreamed(head) {
+		elems, err := r.readStreamed(true)
+		if err != nil {
+			return nil, err
+		}
+		pairs, err := Array(elems).OrderedPairs()
+		if err != nil {
+			return nil, err
+		}
+		return Map(pairs), nil
+	}
+
+	length, err := r.readInt(head)
+	if err != nil {
+		if err == ErrInvalidInt {
+			err = ErrInvalidLength
+		}
+		return nil, err
+	}
+
+	if length == -1 {
+		return Nil, nil
+	} else if length < 0 {
+		return nil, ErrNegativeLength
+	}
+
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+
+	if length == 0 {
+		return Map(nil), nil
+	} else if r.MaxArrayLen > 0 && length > Int(r.MaxArrayLen) {
+		return nil, ErrArrayTooLong
+	}
+
+	m := make([]Pair, 0, preallocLen(length))
+	for i := Int(0); i < length; i++ {
+		var p Pair
+		if p.Key, err = r.read(); err != nil {
+			return nil, err
+		}
+		if p.Value, err = r.read(); err == io.EOF {
+			// The stream ended between a key and its value, leaving an odd number of
+			// elements in the map.
+			return nil, ErrOddMapLength
+		} else if err != nil {
+			return nil, err
+		}
+		m = append(m, p)
+	}
+
+	return Map(m), nil
+}
+
+// isStreamed reports whether head is the header of a RESP3 streamed aggregate, which has a
+// length of "?" and ends with an end marker instead of declaring its length. Streamed
+// aggregates are not accepted in RESP2.
+func (r *Reader) isStreamed(head []byte) bool {
+	return len(head) == 4 && head[1] == '?' && r.proto == RESP3
+}
+
+// isEndMarker reports whether head is the ".\r\n" marker ending a streamed aggregate.
+func isEndMarker(head []byte) bool {
+	return len(head) == 3 && head[0] == '.'
+}
+
+// readStreamed reads the elements of a streamed aggregate up to its end marker. If pairs is
+// true, the aggregate is a map, so MaxArrayLen limits the number of pairs rather than elements.
+func (r *Reader) readStreamed(pairs bool) ([]Msg, error) {
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+
+	max := r.MaxArrayLen
+	if pairs {
+		max *= 2
+	}
+
+	var elems []Msg
+	for {
+		if _, err := r.skipNilCRLF(); err != nil {
+			return nil, err
+		}
+		head, err := r.readHead()
+		if err != nil {
+			return nil, err
+		} else if isEndMarker(head) {
+			return elems, nil
+		} else if max > 0 && len(elems) >= max {
+			return nil, ErrArrayTooLong
+		}
+
+		msg, err := r.readMsg(head)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, msg)
+	}
+}
+
 func (r *Reader) readError(head []byte) (Error, error) {
 	n := len(head) - 2
+	if r.MaxErrorLen > 0 && n-1 > r.MaxErrorLen {
+		return "", ErrErrorTooLong
+	} else if bytes.IndexByte(head[1:n], '\r') >= 0 {
+		return "", ErrInvalidError
+	}
 	return Error(string(head[1:n])), nil
 }
 
+// BytesRead returns the number of bytes of messages consumed from the stream since the Reader
+// was created or last Reset, including the bytes of nested messages and of partially read
+// messages. Bytes buffered from the underlying reader but not yet consumed are not counted.
+// Bytes pushed back by Prepend are counted again when they are read.
+//
+// The count is shared with the Reader returned by ReadCommandName, so it includes command
+// arguments read from that Reader.
+func (r *Reader) BytesRead() int64 {
+	return *r.nread
+}
+
+// LastPrefix returns the type prefix byte of the most recently read top-level message. This
+// preserves the exact wire form of the message (e.g., '+' or '$' for a String). If the last
+// Read failed before reading a prefix, LastPrefix returns 0.
+func (r *Reader) LastPrefix() byte {
+	return r.prefix
+}
+
+// Read reads the next message. Empty bulk strings are read as an empty, non-nil String, which
+// is distinct from the Nil returned for nil bulk strings and arrays; IsNil reports whether a
+// message is nil.
 func (r *Reader) Read() (Msg, error) {
-	head, err := r.r.ReadBytes('\n')
+	if m, ok := r.takeUnread(); ok {
+		return m, nil
+	} else if r.inCustom > 0 {
+		return r.read()
+	}
+
+	head, err := r.readTop()
 	if err != nil {
 		return nil, err
+	}
+	return r.readMsg(head)
+}
+
+// readTop reads the header line of a top-level message.
+func (r *Reader) readTop() ([]byte, error) {
+	if r.stream != nil {
+		return nil, ErrStreamNotDrained
+	}
+
+	if r.cmd {
+		if r.args == 0 {
+			return nil, io.EOF
+		}
+		r.args--
+	}
+
+	r.unwrap()
+	r.prefix = 0
+	r.nextSlab()
+	if !r.cmd {
+		r.start = *r.nread
+		if err := r.startTimeout(); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		if err := r.awaitFirstByte(); err != nil {
+			return nil, err
+		} else if skipped, err := r.skipNilCRLF(); err != nil {
+			return nil, err
+		} else if skipped {
+			continue
+		}
+
+		head, err := r.readHead()
+		if r.inline() && (err == ErrMissingPrefix || err == nil && isBlankInline(head)) {
+			// Blank lines are ignored between inline commands.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		r.prefix = head[0]
+		return head, nil
+	}
+}
+
+// ReadCommandName reads the header and first element of a command array, returning the
+// command name without reading the command's arguments. The arguments are read from rest,
+// which returns io.EOF once all arguments have been read. The caller must read all arguments
+// from rest before reading from r again.
+//
+// If the next message is not a non-empty array beginning with a string, ErrNotCommand is
+// returned and the reader is positioned after the message header.
+func (r *Reader) ReadCommandName() (name string, rest *Reader, err error) {
+	if m, ok := r.takeUnread(); ok {
+		return r.commandFromMsg(m)
+	}
+
+	head, err := r.readTop()
+	if err != nil {
+		return "", nil, err
+	} else if r.inline() && isInline(head) {
+		return r.readInlineCommandName(head)
+	} else if head[0] != '*' {
+		return "", nil, ErrNotCommand
+	}
+
+	length, err := r.readInt(head)
+	if err != nil {
+		if err == ErrInvalidInt {
+			err = ErrInvalidLength
+		}
+		return "", nil, err
+	} else if length < 1 {
+		return "", nil, ErrNotCommand
+	} else if r.MaxArrayLen > 0 && length > Int(r.MaxArrayLen) {
+		return "", nil, ErrArrayTooLong
+	}
+
+	msg, err := r.read()
+	if err != nil {
+		return "", nil, err
+	}
+
+	rest = new(Reader)
+	*rest = *r
+	rest.cmd, rest.args = true, int(length-1)
+
+	if !IsA(msg, TString) {
+		return "", rest, ErrNotCommand
+	}
+	return msg.String(), rest, nil
+}
+
+// argsReader returns a Reader of the n command arguments encoded in buf, for commands read in
+// full before ReadCommandName returns, such as inline and unread commands. It has the options
+// of r, but reads from buf and has no connection, deadlines, or ring of its own.
+func (r *Reader) argsReader(buf *bytes.Buffer, n int) *Reader {
+	rest := new(Reader)
+	*rest = *r
+	rest.src = &source{r: buf}
+	rest.buf, rest.conn, rest.dl = nil, nil, nil
+	rest.nread, rest.start = new(int64), 0
+	rest.prefix, rest.depth = 0, 0
+	rest.stream, rest.unread = nil, nil
+	rest.msg, rest.scanErr, rest.reuse = nil, nil, nil
+	rest.ArrayRing, rest.ring, rest.ringPos = 0, nil, 0
+	rest.slab, rest.last = nil, nil
+	rest.cmd, rest.args = true, n
+	return rest
+}
+
+// ReadAll reads messages until the stream ends, returning all messages read. If reading fails,
+// ReadAll returns the messages read before the failure along with the error. If the stream
+// ends partway through a message, the error is io.ErrUnexpectedEOF.
+func (r *Reader) ReadAll() ([]Msg, error) {
+	var msgs []Msg
+	for r.More() {
+		msg, err := r.Read()
+		if err == io.EOF {
+			return msgs, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// Scan reads the next message, which is then available through Msg. It returns false when the
+// stream ends or reading fails, after which Err returns the error, if any. If the stream ends
+// cleanly at a message boundary, Err returns nil. If it ends partway through a message, Err
+// returns io.ErrUnexpectedEOF. Once Scan returns false, later calls also return false until
+// the Reader is Reset.
+//
+// Scan is a convenience for loops over a stream of messages:
+//
+//	for r.Scan() {
+//		handle(r.Msg())
+//	}
+//	if err := r.Err(); err != nil {
+//		// ...
+//	}
+func (r *Reader) Scan() bool {
+	r.msg = nil
+	if r.scanErr != nil {
+		return false
+	} else if !r.More() {
+		r.scanErr = io.EOF
+		return false
+	}
+
+	msg, err := r.Read()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		r.scanErr = err
+		return false
+	}
+	r.msg = msg
+	return true
+}
+
+// Msg returns the message read by the last call to Scan, or nil if Scan returned false.
+func (r *Reader) Msg() Msg {
+	return r.msg
+}
+
+// Err returns the error that stopped Scan, or nil if Scan stopped at the end of the stream.
+func (r *Reader) Err() error {
+	if r.scanErr == io.EOF {
+		return nil
+	}
+	return r.scanErr
+}
+
+// ReadOrError reads the next message. If the message is an ErrMsg, it is returned as the
+// error with a nil Msg. Decoding errors are returned as-is.
+func (r *Reader) ReadOrError() (Msg, error) {
+	msg, err := r.Read()
+	if err != nil {
+		return nil, err
+	} else if err := ToError(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// ReadReply reads the next reply. It is the same as ReadOrError: a reply that is an ErrMsg,
+// such as "-WRONGTYPE ...", is returned as the error with a nil Msg, so that server errors
+// are handled like any other Go error. Use Read to receive error replies as messages.
+func (r *Reader) ReadReply() (Msg, error) {
+	return r.ReadOrError()
+}
+
+// read reads a message nested inside of an aggregate message.
+func (r *Reader) read() (Msg, error) {
+	if _, err := r.skipNilCRLF(); err != nil {
+		return nil, err
+	}
+	head, err := r.readHead()
+	if err != nil {
+		return nil, err
+	}
+	return r.readMsg(head)
+}
+
+// checkSize returns ErrMessageTooLarge if reading n more bytes would exceed MaxMessageSize.
+func (r *Reader) checkSize(n int64) error {
+	if r.MaxMessageSize > 0 && *r.nread-r.start+n > int64(r.MaxMessageSize) {
+		return ErrMessageTooLarge
+	}
+	return nil
+}
+
+// readHead reads the header line of a message, including its prefix and trailing CRLF.
+func (r *Reader) readHead() ([]byte, error) {
+	head, err := r.src.r.ReadBytes('\n')
+	*r.nread += int64(len(head))
+	if err != nil {
+		return nil, err
+	} else if err := r.checkSize(0); err != nil {
+		return nil, err
 	} else if !bytes.HasSuffix(head, crlf) {
 		return nil, ErrMissingCRLF
 	} else if len(head) == 2 {
 		return nil, ErrMissingPrefix
 	}
+	return head, nil
+}
+
+func (r *Reader) readMsg(head []byte) (Msg, error) {
+	if !r.allowPrefix(head[0]) {
+		return nil, InvalidPrefixError(head[0])
+	}
 
 	switch head[0] {
 	case '-':
-		return r.readError(head)
+		val, err := r.readError(head)
+		if err != nil {
+			return nil, err
+		}
+		return val, nil
 	case '+':
-		return r.readSimpleString(head)
+		val, err := r.readSimpleString(head)
+		if err != nil {
+			return nil, err
+		} else if r.PreserveStringTypes {
+			return SimpleString(val), nil
+		}
+		return val, nil
 	case ':':
 		val, err := r.readInt(head)
 		if err != nil {
@@ -179,7 +1046,31 @@ func (r *Reader) Read() (Msg, error) {
 		return r.readBulkString(head)
 	case '*':
 		return r.readArray(head)
+	case '%':
+		return r.readMap(head)
+	case '~':
+		return r.readSet(head)
+	case '>':
+		return r.readPush(head)
+	case ',':
+		return r.readDouble(head)
+	case '(':
+		return r.readBigNumber(head)
+	case '#':
+		return r.readBool(head)
+	case '|':
+		return r.readAttributed(head)
+	case '_':
+		if len(head) != 3 {
+			return nil, ErrInvalidNull
+		}
+		return Nil, nil
 	default:
+		if m, ok, err := r.readCustom(head); ok {
+			return m, err
+		} else if r.inline() && r.depth == 0 {
+			return readInline(head)
+		}
 		return nil, InvalidPrefixError(head[0])
 	}
 }
@@ -3,13 +3,32 @@ package rdx_test
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"go.spiff.io/rdx"
 )
 
+func bignum(s string) rdx.BigNumber {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid big number: " + s)
+	}
+	return rdx.BigNumber{Int: i}
+}
+
+// newRESP3Reader returns a Reader of rd that accepts RESP3 messages.
+func newRESP3Reader(rd io.Reader) *rdx.Reader {
+	r := rdx.NewReader(rd)
+	r.SetProtocol(rdx.RESP3)
+	return r
+}
+
 type dectest struct {
 	msg    string
 	typ    rdx.Type
@@ -19,7 +38,7 @@ type dectest struct {
 
 func (d *dectest) eval(t *testing.T, nth int) {
 	try := func(br io.Reader) {
-		r := rdx.NewReader(br)
+		r := newRESP3Reader(br)
 		decmsg, err := r.Read()
 
 		if (d.err != nil) != (err != nil) || (d.err != nil && err != nil && d.err != err) {
@@ -43,6 +62,8 @@ func (d *dectest) eval(t *testing.T, nth int) {
 	try(bytes.NewBufferString(d.msg))
 	// Does not trigger the assertion (strings.Reader doesn't implement ReadBytes).
 	try(strings.NewReader(d.msg))
+	// Returns at most one byte per Read, so message bodies arrive in short reads.
+	try(iotest.OneByteReader(strings.NewReader(d.msg)))
 }
 
 func TestReader_Read(t *testing.T) {
@@ -50,9 +71,13 @@ func TestReader_Read(t *testing.T) {
 		// Nil
 		{msg: "$-1\r\n", typ: rdx.TNil, result: rdx.Nil},
 		{msg: "*-1\r\n", typ: rdx.TNil, result: rdx.Nil},
+		{msg: "_\r\n", typ: rdx.TNil, result: rdx.Nil},
+		{msg: "_x\r\n", err: rdx.ErrInvalidNull},
+		{msg: "_\n", err: rdx.ErrMissingCRLF},
+		{msg: "_", err: io.EOF},
 
 		// Bad prefix
-		{msg: "%-1\r\n", err: rdx.InvalidPrefixError('%')},
+		{msg: "@-1\r\n", err: rdx.InvalidPrefixError('@')},
 		{msg: "\r\n", err: rdx.ErrMissingPrefix},
 
 		// Bad suffix
@@ -65,6 +90,8 @@ func TestReader_Read(t *testing.T) {
 		{msg: ":-9223372036854775809\r\n", err: rdx.ErrIntRange},
 		{msg: ":0xff\r\n", err: rdx.ErrInvalidInt},
 		{msg: ":\r\n", err: rdx.ErrEmptyInt},
+		{msg: ":-\r\n", err: rdx.ErrInvalidInt},
+		{msg: ":--1\r\n", err: rdx.ErrInvalidInt},
 		{msg: ":9223372036854775807\r\n", typ: rdx.TInt, result: rdx.Int(1<<63 - 1)},
 		{msg: ":-9223372036854775808\r\n", typ: rdx.TInt, result: rdx.Int(-(1 << 63))},
 		{msg: ":10000000000000000\r\n", typ: rdx.TInt, result: rdx.Int(10000000000000000)},
@@ -72,32 +99,82 @@ func TestReader_Read(t *testing.T) {
 		{msg: ":0\r\n", typ: rdx.TInt, result: rdx.Int(0)},
 
 		// Bulk strings
-		{msg: "$-3\r\n\r\n", err: rdx.ErrInvalidLength},
+		{msg: "$-3\r\n\r\n", err: rdx.ErrNegativeLength},
 		{msg: "$1000000000000000000000000\r\n\r\n", err: rdx.ErrIntRange},
+		{msg: "$9223372036854775806\r\n", err: rdx.ErrBulkTooLarge},
+		{msg: "$9223372036854775807\r\n", err: rdx.ErrBulkTooLarge},
+		{msg: "$?\r\n;9223372036854775806\r\n", err: rdx.ErrBulkTooLarge},
+		{msg: "$?\r\n;3\r\nabc\r\n;9223372036854775805\r\n", err: rdx.ErrBulkTooLarge},
 		{msg: "$f\r\n\r\n", err: rdx.ErrInvalidLength},
-		{msg: "$0\r\n", err: rdx.ErrMissingCRLF},
-		{msg: "$0\r\n\n", err: rdx.ErrMissingCRLF},
-		{msg: "$0\r\n\r", err: rdx.ErrMissingCRLF},
-		{msg: "$0\r\n\r\n", typ: rdx.TBulkString, result: rdx.String(nil)},
+		{msg: "$\r\n", err: rdx.ErrEmptyInt},
+		{msg: "$-\r\n\r\n", err: rdx.ErrInvalidLength},
+		{msg: "$0\r\n", err: io.ErrUnexpectedEOF},
+		{msg: "$0\r\n\n", err: io.ErrUnexpectedEOF},
+		{msg: "$0\r\n\r", err: io.ErrUnexpectedEOF},
+		{msg: "$3\r\nfoo", err: io.ErrUnexpectedEOF},
+		{msg: "$0\r\n\n\r", err: rdx.ErrMissingCRLF},
+		{msg: "$3\r\nfooxx", err: rdx.ErrMissingCRLF},
+		{msg: "$0\r\n\r\n", typ: rdx.TBulkString, result: rdx.String{}},
 		{msg: "$3\r\nfoo\r\n", typ: rdx.TBulkString, result: rdx.String("foo")},
 		{msg: "$22\r\nこんにちは 世界\r\n", typ: rdx.TBulkString, result: rdx.String("こんにちは 世界")},
 
+		// Streamed strings
+		{msg: "$?\r\n;0\r\n", typ: rdx.TBulkString, result: rdx.String{}},
+		{msg: "$?\r\n;5\r\nhello\r\n;0\r\n", typ: rdx.TBulkString, result: rdx.String("hello")},
+		{msg: "$?\r\n;4\r\nHell\r\n;5\r\no wor\r\n;1\r\nd\r\n;0\r\n",
+			typ:    rdx.TBulkString,
+			result: rdx.String("Hello word")},
+		{msg: "$?\r\n;2\r\n\r\n\r\n;0\r\n", typ: rdx.TBulkString, result: rdx.String("\r\n")},
+		{msg: "$?\r\n;3\r\nhello\r\n;0\r\n", err: rdx.ErrMissingCRLF},
+		{msg: "$?\r\n;6\r\nhello\r\n;0\r\n", err: rdx.ErrMissingCRLF},
+		{msg: "$?\r\n;5\r\nhello\r\n", err: io.EOF},
+		{msg: "$?\r\n;5\r\nhel", err: io.ErrUnexpectedEOF},
+		{msg: "$?\r\n$5\r\nhello\r\n;0\r\n", err: rdx.ErrInvalidChunk},
+		{msg: "$?\r\n;-1\r\n", err: rdx.ErrInvalidChunk},
+		{msg: "$?\r\n;x\r\n", err: rdx.ErrInvalidLength},
+		{msg: ";0\r\n", err: rdx.InvalidPrefixError(';')},
+
+		// Big numbers
+		{msg: "(\r\n", err: rdx.ErrInvalidBigNum},
+		{msg: "(-\r\n", err: rdx.ErrInvalidBigNum},
+		{msg: "(12a\r\n", err: rdx.ErrInvalidBigNum},
+		{msg: "(1.5\r\n", err: rdx.ErrInvalidBigNum},
+		{msg: "(3492890328409238509324850943850943825024385\r\n",
+			typ:    rdx.TBigNumber,
+			result: bignum("3492890328409238509324850943850943825024385")},
+		{msg: "(-3492890328409238509324850943850943825024385\r\n",
+			typ:    rdx.TBigNumber,
+			result: bignum("-3492890328409238509324850943850943825024385")},
+		{msg: "(12\r\n", typ: rdx.TBigNumber, result: bignum("12")},
+
+		// Booleans
+		{msg: "#t\r\n", typ: rdx.TBool, result: rdx.Bool(true)},
+		{msg: "#f\r\n", typ: rdx.TBool, result: rdx.Bool(false)},
+		{msg: "#\r\n", err: rdx.ErrInvalidBool},
+		{msg: "#T\r\n", err: rdx.ErrInvalidBool},
+		{msg: "#true\r\n", err: rdx.ErrInvalidBool},
+
 		// Simple strings
-		// These aren't checked for TSimpleString, as the reader will never return
-		// a SimpleString. So, it checks for TString, as this includes both SimpleString and
-		// BulkString.
+		// These aren't checked for TSimpleString, as the reader only returns a SimpleString
+		// with PreserveStringTypes set (see TestReader_PreserveStringTypes). So, it checks for
+		// TString, as this includes both SimpleString and BulkString.
 		{msg: "+こんにちは 世界\r\n", typ: rdx.TString, result: rdx.String("こんにちは 世界")},
 		{msg: "+\r\n", typ: rdx.TString, result: rdx.String("")},
 		{msg: "+\n\r\n", typ: rdx.TString, err: rdx.ErrMissingCRLF},
+		{msg: "+a\rb\r\n", typ: rdx.TString, err: rdx.ErrInvalidSimpleStr},
+		{msg: "+\r\r\n", typ: rdx.TString, err: rdx.ErrInvalidSimpleStr},
 
 		// Errors
 		{msg: "-\r\n", typ: rdx.TError, result: rdx.Error("")},
 		{msg: "-KIND error string\r\n", typ: rdx.TError, result: rdx.Error("KIND error string")},
 		{msg: "-\n\r\n", err: rdx.ErrMissingCRLF},
+		{msg: "-ERR a\rb\r\n", typ: rdx.TError, err: rdx.ErrInvalidError},
 
 		// Arrays
-		{msg: "*-2\r\n", err: rdx.ErrInvalidLength},
+		{msg: "*-2\r\n", err: rdx.ErrNegativeLength},
 		{msg: "*f\r\n\r\n", err: rdx.ErrInvalidLength},
+		{msg: "*-\r\n", err: rdx.ErrInvalidLength},
+		{msg: "*\r\n", err: rdx.ErrEmptyInt},
 		{msg: "*1000000000000000000000000\r\n", err: rdx.ErrIntRange},
 		// Ensure nil on error, and since we have a predictable error here, check for it.
 		{msg: "*4\r\n:123\r\n", err: io.EOF},
@@ -115,9 +192,742 @@ func TestReader_Read(t *testing.T) {
 				rdx.String("foo"),
 				rdx.Error("bar"),
 			})},
+
+		// Sets
+		{msg: "~-2\r\n", err: rdx.ErrNegativeLength},
+		{msg: "~2\r\n:1\r\n", err: io.EOF},
+		{msg: "~0\r\n", typ: rdx.TSet, result: rdx.Set(nil)},
+		{msg: "~2\r\n:1\r\n+a\r\n", typ: rdx.TSet, result: rdx.Set{rdx.Int(1), rdx.String("a")}},
+		{msg: "*1\r\n~1\r\n*0\r\n", typ: rdx.TArray, result: rdx.Array{rdx.Set{rdx.Array(nil)}}},
+		{msg: "%1\r\n~1\r\n:1\r\n~0\r\n",
+			typ:    rdx.TMap,
+			result: rdx.Map{{Key: rdx.Set{rdx.Int(1)}, Value: rdx.Set(nil)}}},
+
+		// Pushes
+		{msg: ">-2\r\n", err: rdx.ErrNegativeLength},
+		{msg: ">2\r\n+message\r\n", err: io.EOF},
+		{msg: ">0\r\n", typ: rdx.TPush, result: rdx.Push(nil)},
+		{msg: ">3\r\n$7\r\nmessage\r\n$7\r\nchannel\r\n$5\r\nhello\r\n",
+			typ:    rdx.TPush,
+			result: rdx.Push{rdx.String("message"), rdx.String("channel"), rdx.String("hello")}},
+
+		// Maps
+		{msg: "%-2\r\n", err: rdx.ErrNegativeLength},
+		{msg: "%f\r\n", err: rdx.ErrInvalidLength},
+		{msg: "%1\r\n+key\r\n", err: rdx.ErrOddMapLength},
+		{msg: "%2\r\n+a\r\n:1\r\n", err: io.EOF},
+		{msg: "%0\r\n", typ: rdx.TMap, result: rdx.Map(nil)},
+		{msg: "%2\r\n+b\r\n:2\r\n+a\r\n$-1\r\n",
+			typ: rdx.TMap,
+			result: rdx.Map{
+				{Key: rdx.String("b"), Value: rdx.Int(2)},
+				{Key: rdx.String("a"), Value: rdx.Nil},
+			}},
+		{msg: "*2\r\n%1\r\n+k\r\n*1\r\n:1\r\n:2\r\n",
+			typ: rdx.TArray,
+			result: rdx.Array{
+				rdx.Map{{Key: rdx.String("k"), Value: rdx.Array{rdx.Int(1)}}},
+				rdx.Int(2),
+			}},
+
+		// Streamed aggregates
+		{msg: "*?\r\n.\r\n", typ: rdx.TArray, result: rdx.Array(nil)},
+		{msg: "*?\r\n:1\r\n$1\r\na\r\n+b\r\n_\r\n*?\r\n#t\r\n.\r\n*1\r\n:2\r\n.\r\n",
+			typ: rdx.TArray,
+			result: rdx.Array{
+				rdx.Int(1),
+				rdx.String("a"),
+				rdx.String("b"),
+				rdx.Nil,
+				rdx.Array{rdx.Bool(true)},
+				rdx.Array{rdx.Int(2)},
+			}},
+		{msg: "*?\r\n:1\r\n", err: io.EOF},
+		{msg: ".\r\n", err: rdx.InvalidPrefixError('.')},
+		{msg: "*1\r\n.\r\n", err: rdx.InvalidPrefixError('.')},
+		{msg: "*??\r\n.\r\n", err: rdx.ErrInvalidLength},
+		{msg: "~?\r\n:1\r\n.\r\n", typ: rdx.TSet, result: rdx.Set{rdx.Int(1)}},
+		{msg: ">?\r\n.\r\n", typ: rdx.TPush, result: rdx.Push(nil)},
+		{msg: "%?\r\n.\r\n", typ: rdx.TMap, result: rdx.Map(nil)},
+		{msg: "%?\r\n+a\r\n:1\r\n.\r\n", typ: rdx.TMap, result: rdx.Map{{Key: rdx.String("a"), Value: rdx.Int(1)}}},
+		{msg: "%?\r\n+a\r\n.\r\n", err: rdx.ErrOddMapLength},
 	}
 
 	for i, d := range table {
 		d.eval(t, i)
 	}
 }
+
+func TestReader_ReadDouble(t *testing.T) {
+	table := []dectest{
+		{msg: ",\r\n", err: rdx.ErrEmptyDouble},
+		{msg: ",1.5x\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",1.5 \r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",1.5.5\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",-\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",e3\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",0x1p-2\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",1_000\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",Infinity\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",+inf\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",infx\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",-nan\r\n", err: rdx.ErrInvalidDouble},
+		{msg: ",1e400\r\n", err: rdx.ErrDoubleRange},
+		{msg: ",-1e400\r\n", err: rdx.ErrDoubleRange},
+		{msg: ",1.8e308\r\n", err: rdx.ErrDoubleRange},
+		{msg: ",3.14\r\n", typ: rdx.TDouble, result: rdx.Double(3.14)},
+		{msg: ",-2\r\n", typ: rdx.TDouble, result: rdx.Double(-2)},
+		{msg: ",+2\r\n", typ: rdx.TDouble, result: rdx.Double(2)},
+		{msg: ",0\r\n", typ: rdx.TDouble, result: rdx.Double(0)},
+		{msg: ",1.5e3\r\n", typ: rdx.TDouble, result: rdx.Double(1500)},
+		{msg: ",3.0E3\r\n", typ: rdx.TDouble, result: rdx.Double(3000)},
+		{msg: ",2.5e-3\r\n", typ: rdx.TDouble, result: rdx.Double(0.0025)},
+		{msg: ",1.7976931348623157e308\r\n", typ: rdx.TDouble, result: rdx.Double(math.MaxFloat64)},
+		{msg: ",1e-400\r\n", typ: rdx.TDouble, result: rdx.Double(0)},
+		{msg: ",inf\r\n", typ: rdx.TDouble, result: rdx.Double(math.Inf(1))},
+		{msg: ",INF\r\n", typ: rdx.TDouble, result: rdx.Double(math.Inf(1))},
+		{msg: ",-inf\r\n", typ: rdx.TDouble, result: rdx.Double(math.Inf(-1))},
+		{msg: ",-Inf\r\n", typ: rdx.TDouble, result: rdx.Double(math.Inf(-1))},
+	}
+
+	for i, d := range table {
+		d.eval(t, i)
+	}
+}
+
+func TestReader_ReadNaN(t *testing.T) {
+	for _, in := range []string{"nan", "NaN", "NAN"} {
+		msg, err := newRESP3Reader(strings.NewReader("," + in + "\r\n")).Read()
+		if err != nil {
+			t.Fatalf("Read(%q) err = %v; want nil", in, err)
+		}
+		if d, ok := msg.(rdx.Double); !ok || !math.IsNaN(float64(d)) {
+			t.Fatalf("Read(%q) = %#v; want Double(NaN)", in, msg)
+		}
+	}
+}
+
+func TestReader_LastPrefix(t *testing.T) {
+	r := newRESP3Reader(strings.NewReader(strings.Join([]string{
+		"+simple",
+		"$4\r\nbulk",
+		":1",
+		"-ERR",
+		"*1\r\n$-1",
+		"%0",
+		"~0",
+		",1.5",
+		"@",
+		"", // sentinel
+	}, "\r\n")))
+
+	for i, want := range []byte{'+', '$', ':', '-', '*', '%', '~', ','} {
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("[%d] Read() err = %v; want nil", i, err)
+		}
+		if got := r.LastPrefix(); got != want {
+			t.Errorf("[%d] LastPrefix() = %q; want %q", i, got, want)
+		}
+	}
+
+	// Invalid prefixes are still recorded.
+	if _, err := r.Read(); err == nil {
+		t.Fatal("Read() err = nil; want InvalidPrefixError")
+	} else if got := r.LastPrefix(); got != '@' {
+		t.Errorf("LastPrefix() = %q; want '@'", got)
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("Read() err = %v; want EOF", err)
+	} else if got := r.LastPrefix(); got != 0 {
+		t.Errorf("LastPrefix() = %q; want 0", got)
+	}
+}
+
+func TestReader_ReadOrError(t *testing.T) {
+	r := rdx.NewReader(strings.NewReader("-ERR failed\r\n:1\r\n@\r\n"))
+
+	if msg, err := r.ReadOrError(); msg != nil || err != rdx.Error("ERR failed") {
+		t.Errorf("ReadOrError() = %#v, %v; want nil, ERR failed", msg, err)
+	}
+
+	if msg, err := r.ReadOrError(); msg != rdx.Int(1) || err != nil {
+		t.Errorf("ReadOrError() = %#v, %v; want 1, nil", msg, err)
+	}
+
+	if msg, err := r.ReadOrError(); msg != nil || err != rdx.InvalidPrefixError('@') {
+		t.Errorf("ReadOrError() = %#v, %v; want nil, InvalidPrefixError('@')", msg, err)
+	}
+}
+
+func TestReader_PreserveStringTypes(t *testing.T) {
+	const stream = "+OK\r\n$2\r\nOK\r\n+\r\n*2\r\n+a\r\n$1\r\nb\r\n+a\rb\r\n"
+	r := rdx.NewReader(strings.NewReader(stream))
+	r.PreserveStringTypes = true
+
+	for i, want := range []rdx.Msg{
+		rdx.SimpleString("OK"),
+		rdx.String("OK"),
+		rdx.SimpleString(""),
+		rdx.Array{rdx.SimpleString("a"), rdx.String("b")},
+	} {
+		msg, err := r.Read()
+		if err != nil || !reflect.DeepEqual(msg, want) {
+			t.Fatalf("[%d] Read() = %#v, %v; want %#v, nil", i, msg, err, want)
+		}
+	}
+	if msg, err := r.Read(); err != rdx.ErrInvalidSimpleStr {
+		t.Fatalf("Read() = %#v, %v; want nil, %v", msg, err, rdx.ErrInvalidSimpleStr)
+	}
+
+	// Strings are collapsed by default.
+	r = rdx.NewReader(strings.NewReader(stream))
+	if msg, err := r.Read(); err != nil || !reflect.DeepEqual(msg, rdx.String("OK")) {
+		t.Fatalf("Read() = %#v, %v; want %#v, nil", msg, err, rdx.String("OK"))
+	}
+}
+
+func TestReader_ReadReply(t *testing.T) {
+	r := newRESP3Reader(strings.NewReader("-WRONGTYPE bad\r\n|1\r\n+k\r\n+v\r\n-ERR attr\r\n+OK\r\n"))
+
+	if msg, err := r.ReadReply(); msg != nil || err != rdx.Error("WRONGTYPE bad") {
+		t.Errorf("ReadReply() = %#v, %v; want nil, WRONGTYPE bad", msg, err)
+	}
+
+	if msg, err := r.ReadReply(); msg != nil || err != rdx.Error("ERR attr") {
+		t.Errorf("ReadReply() = %#v, %v; want nil, ERR attr", msg, err)
+	}
+
+	if msg, err := r.ReadReply(); err != nil || !rdx.Equal(msg, rdx.SimpleString("OK")) {
+		t.Errorf("ReadReply() = %#v, %v; want OK, nil", msg, err)
+	}
+}
+
+func TestReader_LegacyNil(t *testing.T) {
+	stream := "$-1\r\n\r\n" +
+		":1\r\n" +
+		"*3\r\n$-1\r\n\r\n$-1\r\n:2\r\n" +
+		"*?\r\n$-1\r\n\r\n.\r\n" +
+		"$-1\r\n\r\n" +
+		"$-1\r\n\r\n"
+	r := newRESP3Reader(iotest.OneByteReader(strings.NewReader(stream)))
+
+	if msg, err := r.Read(); err != nil || msg != rdx.Nil {
+		t.Fatalf("Read() = %v, %v; want nil, nil", msg, err)
+	}
+	if typ, err := r.PeekType(); err != nil || typ != rdx.TInt {
+		t.Fatalf("PeekType() = %v, %v; want %v, nil", typ, err, rdx.TInt)
+	}
+	if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+		t.Fatalf("Read() = %v, %v; want 1, nil", msg, err)
+	}
+
+	want := rdx.Array{rdx.Nil, rdx.Nil, rdx.Int(2)}
+	if msg, err := r.Read(); err != nil || !reflect.DeepEqual(msg, want) {
+		t.Fatalf("Read() = %v, %v; want %v, nil", msg, err, want)
+	}
+	if msg, err := r.Read(); err != nil || !reflect.DeepEqual(msg, rdx.Array{rdx.Nil}) {
+		t.Fatalf("Read() = %v, %v; want [nil], nil", msg, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if !r.More() {
+			t.Fatalf("[%d] More() = false; want true", i)
+		}
+		if msg, err := r.Read(); err != nil || msg != rdx.Nil {
+			t.Fatalf("[%d] Read() = %v, %v; want nil, nil", i, msg, err)
+		}
+	}
+	if r.More() {
+		t.Fatal("More() = true at end of stream; want false")
+	}
+
+	// Only one empty line is consumed, and only after a nil bulk string.
+	for _, c := range []struct {
+		stream string
+		err    error
+	}{
+		{"$-1\r\n\r\n\r\n:1\r\n", rdx.ErrMissingPrefix},
+		{"$-1\r\n\rx:1\r\n", rdx.ErrMissingCRLF},
+		{"$0\r\n\r\n\r\n:1\r\n", rdx.ErrMissingPrefix},
+	} {
+		r := rdx.NewReader(strings.NewReader(c.stream))
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("%q: Read() err = %v", c.stream, err)
+		}
+		if msg, err := r.Read(); err != c.err {
+			t.Errorf("%q: Read() = %v, %v; want nil, %v", c.stream, msg, err, c.err)
+		}
+	}
+}
+
+func TestReader_MaxBulkSize(t *testing.T) {
+	table := []struct {
+		max    int
+		msg    string
+		result rdx.Msg
+		err    error
+	}{
+		{max: 0, msg: "$5\r\nhello\r\n", result: rdx.String("hello")},
+		{max: 5, msg: "$5\r\nhello\r\n", result: rdx.String("hello")},
+		{max: 4, msg: "$5\r\nhello\r\n", err: rdx.ErrBulkTooLarge},
+		{max: 4, msg: "$1000000000\r\n", err: rdx.ErrBulkTooLarge},
+		{max: 4, msg: "$-1\r\n", result: rdx.Nil},
+		{max: 4, msg: "*2\r\n$1\r\na\r\n$5\r\nhello\r\n", err: rdx.ErrBulkTooLarge},
+		{max: 5, msg: "$?\r\n;3\r\nhel\r\n;2\r\nlo\r\n;0\r\n", result: rdx.String("hello")},
+		{max: 4, msg: "$?\r\n;3\r\nhel\r\n;2\r\nlo\r\n;0\r\n", err: rdx.ErrBulkTooLarge},
+	}
+
+	for i, c := range table {
+		r := newRESP3Reader(strings.NewReader(c.msg))
+		r.MaxBulkSize = c.max
+		msg, err := r.Read()
+		if err != c.err {
+			t.Errorf("[%d] Read() err = %v; want %v", i, err, c.err)
+		}
+		if !reflect.DeepEqual(msg, c.result) {
+			t.Errorf("[%d] Read() = %#v; want %#v", i, msg, c.result)
+		}
+	}
+}
+
+func TestReader_ReadCommandName(t *testing.T) {
+	r := rdx.NewReader(strings.NewReader(
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n" +
+			"*1\r\n+PING\r\n" +
+			":1\r\n"))
+
+	name, rest, err := r.ReadCommandName()
+	if err != nil || name != "SET" {
+		t.Fatalf("ReadCommandName() = %q, _, %v; want SET, _, nil", name, err)
+	}
+
+	var args []rdx.Msg
+	for {
+		msg, err := rest.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("rest.Read() err = %v", err)
+		}
+		args = append(args, msg)
+	}
+	if want := []rdx.Msg{rdx.String("key"), rdx.String("value")}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("rest args = %v; want %v", args, want)
+	}
+
+	name, rest, err = r.ReadCommandName()
+	if err != nil || name != "PING" {
+		t.Fatalf("ReadCommandName() = %q, _, %v; want PING, _, nil", name, err)
+	}
+	if msg, err := rest.Read(); err != io.EOF {
+		t.Fatalf("rest.Read() = %v, %v; want EOF", msg, err)
+	}
+
+	if _, _, err = r.ReadCommandName(); err != rdx.ErrNotCommand {
+		t.Fatalf("ReadCommandName() err = %v; want %v", err, rdx.ErrNotCommand)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("Read() err = %v; want EOF", err)
+	}
+}
+
+func TestReader_ReadCommandName_shared(t *testing.T) {
+	// byteReader can't unread bytes, so peeking from rest wraps the source in a bufio.Reader,
+	// which r must read from too.
+	r := rdx.NewReader(byteReader{strings.NewReader(
+		"*3\r\n$3\r\nGET\r\n$1\r\nk\r\n$-1\r\n\r\n" +
+			":1\r\n")})
+
+	name, rest, err := r.ReadCommandName()
+	if err != nil || name != "GET" {
+		t.Fatalf("ReadCommandName() = %q, _, %v; want GET, _, nil", name, err)
+	}
+	if typ, err := rest.PeekType(); err != nil || typ != rdx.TBulkString {
+		t.Fatalf("rest.PeekType() = %v, %v; want %v, nil", typ, err, rdx.TBulkString)
+	}
+	if msg, err := rest.Read(); err != nil || !rdx.Equal(msg, rdx.String("k")) {
+		t.Fatalf("rest.Read() = %v, %v; want k, nil", msg, err)
+	}
+	// The empty line after the nil bulk string is skipped by r.
+	if msg, err := rest.Read(); err != nil || msg != rdx.Nil {
+		t.Fatalf("rest.Read() = %v, %v; want nil, nil", msg, err)
+	}
+	if rest.More() {
+		t.Fatal("rest.More() = true; want false")
+	}
+	if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+		t.Fatalf("Read() = %v, %v; want 1, nil", msg, err)
+	}
+	if r.More() {
+		t.Fatal("More() = true; want false")
+	}
+}
+
+func TestReader_MaxArrayLen(t *testing.T) {
+	table := []struct {
+		max    int
+		msg    string
+		result rdx.Msg
+		err    error
+	}{
+		{max: 0, msg: "*2\r\n:1\r\n:2\r\n", result: rdx.Array{rdx.Int(1), rdx.Int(2)}},
+		{max: 2, msg: "*2\r\n:1\r\n:2\r\n", result: rdx.Array{rdx.Int(1), rdx.Int(2)}},
+		{max: 1, msg: "*2\r\n:1\r\n:2\r\n", err: rdx.ErrArrayTooLong},
+		{max: 1, msg: "*2000000000\r\n", err: rdx.ErrArrayTooLong},
+		{max: 1, msg: "*-1\r\n", result: rdx.Nil},
+		{max: 1, msg: "*1\r\n*2\r\n:1\r\n:2\r\n", err: rdx.ErrArrayTooLong},
+		{max: 1, msg: "~2\r\n:1\r\n:2\r\n", err: rdx.ErrArrayTooLong},
+		{max: 1, msg: ">2\r\n:1\r\n:2\r\n", err: rdx.ErrArrayTooLong},
+		{max: 1, msg: "%2\r\n:1\r\n:2\r\n:3\r\n:4\r\n", err: rdx.ErrArrayTooLong},
+		{max: 1, msg: "%1\r\n:1\r\n:2\r\n", result: rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}},
+		// Declared lengths aren't trusted for allocation even without a limit.
+		{max: 0, msg: "*2000000000\r\n:1\r\n", err: io.EOF},
+		{max: 1, msg: "*?\r\n:1\r\n.\r\n", result: rdx.Array{rdx.Int(1)}},
+		{max: 1, msg: "*?\r\n:1\r\n:2\r\n.\r\n", err: rdx.ErrArrayTooLong},
+		{max: 1, msg: "%?\r\n:1\r\n:2\r\n.\r\n", result: rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}},
+		{max: 1, msg: "%?\r\n:1\r\n:2\r\n:3\r\n:4\r\n.\r\n", err: rdx.ErrArrayTooLong},
+	}
+
+	for i, c := range table {
+		r := newRESP3Reader(strings.NewReader(c.msg))
+		r.MaxArrayLen = c.max
+		msg, err := r.Read()
+		if err != c.err {
+			t.Errorf("[%d] Read() err = %v; want %v", i, err, c.err)
+		}
+		if !reflect.DeepEqual(msg, c.result) {
+			t.Errorf("[%d] Read() = %#v; want %#v", i, msg, c.result)
+		}
+	}
+}
+
+func TestReader_MaxSimpleLen(t *testing.T) {
+	table := []struct {
+		simple, errlen int
+		msg            string
+		result         rdx.Msg
+		err            error
+	}{
+		{msg: "+" + strings.Repeat("x", 100) + "\r\n", result: rdx.String(strings.Repeat("x", 100))},
+		{simple: 3, msg: "+abc\r\n", result: rdx.String("abc")},
+		{simple: 3, msg: "+abcd\r\n", err: rdx.ErrSimpleTooLong},
+		{simple: 3, msg: "+\r\n", result: rdx.String("")},
+		{simple: 3, msg: "-ERRORS\r\n", result: rdx.Error("ERRORS")},
+		{simple: 3, msg: "*1\r\n+abcd\r\n", err: rdx.ErrSimpleTooLong},
+		{errlen: 3, msg: "-ERR\r\n", result: rdx.Error("ERR")},
+		{errlen: 3, msg: "-ERRS\r\n", err: rdx.ErrErrorTooLong},
+		{errlen: 3, msg: "+abcd\r\n", result: rdx.String("abcd")},
+	}
+
+	for i, c := range table {
+		r := rdx.NewReader(strings.NewReader(c.msg))
+		r.MaxSimpleLen = c.simple
+		r.MaxErrorLen = c.errlen
+		msg, err := r.Read()
+		if err != c.err {
+			t.Errorf("[%d] Read() err = %v; want %v", i, err, c.err)
+		}
+		if !reflect.DeepEqual(msg, c.result) {
+			t.Errorf("[%d] Read() = %#v; want %#v", i, msg, c.result)
+		}
+	}
+}
+
+func TestReader_MaxMessageSize(t *testing.T) {
+	table := []struct {
+		max    int
+		bulk   int
+		msg    string
+		result rdx.Msg
+		err    error
+	}{
+		{max: 0, msg: "$3\r\nfoo\r\n", result: rdx.String("foo")},
+		{max: 9, msg: "$3\r\nfoo\r\n", result: rdx.String("foo")},
+		{max: 8, msg: "$3\r\nfoo\r\n", err: rdx.ErrMessageTooLarge},
+		{max: 8, msg: "$1000000000\r\n", err: rdx.ErrMessageTooLarge},
+		{max: 4, msg: ":1\r\n", result: rdx.Int(1)},
+		{max: 4, msg: ":10\r\n", err: rdx.ErrMessageTooLarge},
+		{max: 5, msg: "+OK\r\n", result: rdx.String("OK")},
+		// Nested messages count towards the total, even if each passes MaxBulkSize.
+		{max: 22, bulk: 3, msg: "*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n", result: rdx.Array{rdx.String("foo"), rdx.String("bar")}},
+		{max: 21, bulk: 3, msg: "*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n", err: rdx.ErrMessageTooLarge},
+		{max: 21, bulk: 2, msg: "*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n", err: rdx.ErrBulkTooLarge},
+		{max: 12, msg: "*3\r\n:1\r\n:2\r\n:3\r\n", err: rdx.ErrMessageTooLarge},
+		// The limit is reset for each message.
+		{max: 4, msg: ":1\r\n:2\r\n", result: rdx.Int(1)},
+	}
+
+	for i, c := range table {
+		r := rdx.NewReader(strings.NewReader(c.msg))
+		r.MaxMessageSize = c.max
+		r.MaxBulkSize = c.bulk
+		msg, err := r.Read()
+		if err != c.err {
+			t.Errorf("[%d] Read() err = %v; want %v", i, err, c.err)
+		}
+		if !reflect.DeepEqual(msg, c.result) {
+			t.Errorf("[%d] Read() = %#v; want %#v", i, msg, c.result)
+		}
+		if c.err == nil && r.More() {
+			if _, err := r.Read(); err != nil {
+				t.Errorf("[%d] second Read() err = %v", i, err)
+			}
+		}
+	}
+}
+
+func TestReader_MaxDepth(t *testing.T) {
+	table := []struct {
+		max    int
+		msg    string
+		result rdx.Msg
+		err    error
+	}{
+		{max: 1, msg: "*1\r\n:1\r\n", result: rdx.Array{rdx.Int(1)}},
+		{max: 1, msg: "*1\r\n*0\r\n", err: rdx.ErrMaxDepthExceeded},
+		{max: 2, msg: "*1\r\n*0\r\n", result: rdx.Array{rdx.Array(nil)}},
+		{max: 2, msg: "*2\r\n*0\r\n*0\r\n", result: rdx.Array{rdx.Array(nil), rdx.Array(nil)}},
+		{max: 2, msg: "%1\r\n~1\r\n:1\r\n>1\r\n:2\r\n",
+			result: rdx.Map{{Key: rdx.Set{rdx.Int(1)}, Value: rdx.Push{rdx.Int(2)}}}},
+		{max: 2, msg: "%1\r\n:1\r\n~1\r\n>0\r\n", err: rdx.ErrMaxDepthExceeded},
+		{max: 2, msg: "~1\r\n>1\r\n*0\r\n", err: rdx.ErrMaxDepthExceeded},
+		{max: 100, msg: strings.Repeat("*1\r\n", 1000000) + ":1\r\n", err: rdx.ErrMaxDepthExceeded},
+	}
+
+	for i, c := range table {
+		r := newRESP3Reader(strings.NewReader(c.msg))
+		r.MaxDepth = c.max
+		msg, err := r.Read()
+		if err != c.err {
+			t.Errorf("[%d] Read() err = %v; want %v", i, err, c.err)
+		}
+		if !reflect.DeepEqual(msg, c.result) {
+			t.Errorf("[%d] Read() = %#v; want %#v", i, msg, c.result)
+		}
+	}
+
+	// Depth is tracked per message, so sibling and subsequent messages are unaffected.
+	r := rdx.NewReader(strings.NewReader("*1\r\n*0\r\n*1\r\n*0\r\n"))
+	r.MaxDepth = 2
+	for i := 0; i < 2; i++ {
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("[%d] Read() err = %v", i, err)
+		}
+	}
+}
+
+func TestLengthErrors(t *testing.T) {
+	if rdx.ErrBadLength != rdx.ErrInvalidLength {
+		t.Errorf("ErrBadLength = %v; want ErrInvalidLength", rdx.ErrBadLength)
+	}
+	if rdx.ErrInvalidLength.Error() == rdx.ErrInvalidInt.Error() {
+		t.Errorf("ErrInvalidLength and ErrInvalidInt have the same message %q", rdx.ErrInvalidInt)
+	}
+
+	// Malformed lengths and integers are reported separately.
+	for _, c := range []struct {
+		in  string
+		err error
+	}{
+		{"$x\r\n", rdx.ErrInvalidLength},
+		{"*x\r\n", rdx.ErrInvalidLength},
+		{":x\r\n", rdx.ErrInvalidInt},
+	} {
+		if _, err := rdx.NewReader(strings.NewReader(c.in)).Read(); err != c.err {
+			t.Errorf("Read(%q) err = %v; want %v", c.in, err, c.err)
+		}
+	}
+}
+
+func TestReader_ReadAll(t *testing.T) {
+	table := []struct {
+		in   string
+		want []rdx.Msg
+		err  error
+	}{
+		{"", nil, nil},
+		{":1\r\n+OK\r\n*1\r\n$-1\r\n", []rdx.Msg{rdx.Int(1), rdx.String("OK"), rdx.Array{rdx.Nil}}, nil},
+		{":1\r\n+OK", []rdx.Msg{rdx.Int(1)}, io.ErrUnexpectedEOF},
+		{":1\r\n*2\r\n:1\r\n", []rdx.Msg{rdx.Int(1)}, io.ErrUnexpectedEOF},
+		{":1\r\n:x\r\n:2\r\n", []rdx.Msg{rdx.Int(1)}, rdx.ErrInvalidInt},
+	}
+
+	for i, c := range table {
+		got, err := rdx.NewReader(strings.NewReader(c.in)).ReadAll()
+		if err != c.err {
+			t.Errorf("[%d] ReadAll() err = %v; want %v", i, err, c.err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%d] ReadAll() = %#v; want %#v", i, got, c.want)
+		}
+	}
+}
+
+func TestReader_Scan(t *testing.T) {
+	table := []struct {
+		in   string
+		want []rdx.Msg
+		err  error
+	}{
+		{"", nil, nil},
+		{":1\r\n+OK\r\n*1\r\n$-1\r\n", []rdx.Msg{rdx.Int(1), rdx.String("OK"), rdx.Array{rdx.Nil}}, nil},
+		{":1\r\n+OK", []rdx.Msg{rdx.Int(1)}, io.ErrUnexpectedEOF},
+		{":1\r\n:x\r\n:2\r\n", []rdx.Msg{rdx.Int(1)}, rdx.ErrInvalidInt},
+	}
+
+	for i, c := range table {
+		r := rdx.NewReader(strings.NewReader(c.in))
+		var got []rdx.Msg
+		for r.Scan() {
+			got = append(got, r.Msg())
+		}
+		if err := r.Err(); err != c.err {
+			t.Errorf("[%d] Err() = %v; want %v", i, err, c.err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%d] Scan() read %#v; want %#v", i, got, c.want)
+		}
+
+		// Scanning stops for good.
+		if r.Scan() || r.Msg() != nil {
+			t.Errorf("[%d] Scan() = true, %v after stopping; want false, nil", i, r.Msg())
+		}
+	}
+}
+
+func TestReader_BytesRead(t *testing.T) {
+	msgs := []string{
+		":1\r\n",
+		"*2\r\n$3\r\nfoo\r\n%1\r\n+k\r\n,1.5\r\n",
+		"$-1\r\n",
+		"-ERR x\r\n",
+	}
+	r := newRESP3Reader(iotest.OneByteReader(strings.NewReader(strings.Join(msgs, "") + "$5\r\nab")))
+
+	var want int64
+	for i, m := range msgs {
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("[%d] Read() err = %v", i, err)
+		}
+		want += int64(len(m))
+		if got := r.BytesRead(); got != want {
+			t.Fatalf("[%d] BytesRead() = %d; want %d", i, got, want)
+		}
+	}
+
+	// Partially read messages are counted.
+	if _, err := r.Read(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Read() err = %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+	if got, want := r.BytesRead(), want+6; got != want {
+		t.Fatalf("BytesRead() = %d; want %d", got, want)
+	}
+
+	// Command arguments and streamed bulk strings are counted.
+	cmd := "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"
+	r.Reset(strings.NewReader(cmd + "$3\r\nabc\r\n"))
+	if r.BytesRead() != 0 {
+		t.Fatalf("BytesRead() = %d after Reset; want 0", r.BytesRead())
+	}
+	_, rest, err := r.ReadCommandName()
+	if err != nil {
+		t.Fatalf("ReadCommandName() err = %v", err)
+	}
+	for rest.More() {
+		rest.Read()
+	}
+	if got, want := r.BytesRead(), int64(len(cmd)); got != want {
+		t.Fatalf("BytesRead() = %d; want %d", got, want)
+	}
+
+	body, _, err := r.ReadStream()
+	if err != nil {
+		t.Fatalf("ReadStream() err = %v", err)
+	}
+	ioutil.ReadAll(body)
+	if got, want := r.BytesRead(), int64(len(cmd)+9); got != want {
+		t.Fatalf("BytesRead() = %d; want %d", got, want)
+	}
+}
+
+func TestReader_Reset(t *testing.T) {
+	r := rdx.NewReader(strings.NewReader(":1\r\n:2\r\n"))
+	r.MaxBulkSize = 3
+
+	if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+		t.Fatalf("Read() = %v, %v; want 1, nil", msg, err)
+	}
+
+	// Buffered data from the previous source is discarded.
+	r.Reset(strings.NewReader(":3\r\n"))
+	if msg, err := r.Read(); err != nil || msg != rdx.Int(3) {
+		t.Fatalf("Read() = %v, %v; want 3, nil", msg, err)
+	}
+	if msg, err := r.Read(); err != io.EOF {
+		t.Fatalf("Read() = %v, %v; want EOF", msg, err)
+	}
+
+	// Options are kept across resets, including to a bytesReader.
+	r.Reset(bytes.NewBufferString("$4\r\nfour\r\n"))
+	if _, err := r.Read(); err != rdx.ErrBulkTooLarge {
+		t.Fatalf("Read() err = %v; want %v", err, rdx.ErrBulkTooLarge)
+	}
+
+	r.Reset(strings.NewReader("+ok\r\n"))
+	if msg, err := r.Read(); err != nil || msg.String() != "ok" {
+		t.Fatalf("Read() = %v, %v; want ok, nil", msg, err)
+	}
+}
+
+// sizeReader records the size of the first buffer passed to Read.
+type sizeReader struct {
+	io.Reader
+	first int
+}
+
+func (r *sizeReader) Read(p []byte) (int, error) {
+	if r.first == 0 {
+		r.first = len(p)
+	}
+	return r.Reader.Read(p)
+}
+
+func TestNewReaderSize(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	for _, c := range []struct{ size, want int }{{1, 16}, {16, 16}, {64, 64}, {8192, 8192}} {
+		src := &sizeReader{Reader: strings.NewReader("$100\r\n" + long + "\r\n:1\r\n")}
+		r := rdx.NewReaderSize(src, c.size)
+		if msg, err := r.Read(); err != nil || msg.String() != long {
+			t.Fatalf("NewReaderSize(%d): Read() = %.10q, %v; want %.10q, nil", c.size, msg, err, long)
+		}
+		if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+			t.Fatalf("NewReaderSize(%d): Read() = %v, %v; want 1, nil", c.size, msg, err)
+		}
+		if src.first != c.want {
+			t.Errorf("NewReaderSize(%d) buffer size = %d; want %d", c.size, src.first, c.want)
+		}
+
+		// The buffer is kept across resets.
+		src = &sizeReader{Reader: strings.NewReader(":2\r\n")}
+		r.Reset(src)
+		if msg, err := r.Read(); err != nil || msg != rdx.Int(2) || src.first != c.want {
+			t.Errorf("NewReaderSize(%d) after Reset: Read() = %v, %v, buffer size %d; want 2, nil, %d",
+				c.size, msg, err, src.first, c.want)
+		}
+	}
+}
+
+func BenchmarkReader_Reset(b *testing.B) {
+	r := rdx.NewReader(nil)
+	src := strings.NewReader(":1\r\n")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		src.Reset(":1\r\n")
+		r.Reset(src)
+		if _, err := r.Read(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package rdx_test
 
 import (
+	"bufio"
 	"bytes"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -42,6 +46,10 @@ func TestWrite_encoding(t *testing.T) {
 	table := []enctest{
 		{nil, "$-1\r\n", nil},
 		{rdx.Nil, "$-1\r\n", nil},
+		{rdx.Null, "_\r\n", nil},
+		{rdx.Array{rdx.Null, rdx.Nil}, "*2\r\n_\r\n$-1\r\n", nil},
+		{rdx.NilArray, "*-1\r\n", nil},
+		{rdx.Array{rdx.NilArray}, "*1\r\n*-1\r\n", nil},
 
 		{rdx.Error("nonempty"), "-nonempty\r\n", nil},
 		{rdx.Error("KIND nonempty"), "-KIND nonempty\r\n", nil},
@@ -62,6 +70,19 @@ func TestWrite_encoding(t *testing.T) {
 		{rdx.SimpleString("hello world"), "+hello world\r\n", nil},
 		{rdx.SimpleString("\n"), "$1\r\n\n\r\n", nil},
 		{rdx.SimpleString("\r"), "$1\r\n\r\r\n", nil},
+		{rdx.SimpleString("\x00"), "$1\r\n\x00\r\n", nil},
+		{rdx.SimpleString("a\tb"), "$3\r\na\tb\r\n", nil},
+		{rdx.SimpleString("\x1f"), "$1\r\n\x1f\r\n", nil},
+		{rdx.SimpleString("\x7fé"), "+\x7fé\r\n", nil},
+
+		{rdx.AutoString(""), "+\r\n", nil},
+		{rdx.AutoString("OK"), "+OK\r\n", nil},
+		{rdx.AutoString(strings.Repeat("x", rdx.MaxAutoSimpleLen)),
+			"+" + strings.Repeat("x", rdx.MaxAutoSimpleLen) + "\r\n", nil},
+		{rdx.AutoString(strings.Repeat("x", rdx.MaxAutoSimpleLen+1)),
+			"$65\r\n" + strings.Repeat("x", rdx.MaxAutoSimpleLen+1) + "\r\n", nil},
+		{rdx.AutoString("a\r\nb"), "$4\r\na\r\nb\r\n", nil},
+		{rdx.AutoString("a\nb"), "$3\r\na\nb\r\n", nil},
 
 		{rdx.Array([]rdx.Msg{nil, rdx.Nil}), "*2\r\n$-1\r\n$-1\r\n", nil},
 		{rdx.Array([]rdx.Msg{rdx.Error("\r")}), "", rdx.ErrInvalidError},
@@ -84,9 +105,260 @@ func TestWrite_encoding(t *testing.T) {
 				"", // sentinel
 			}, "\r\n"),
 			nil},
+
+		{rdx.Double(3.14), ",3.14\r\n", nil},
+		{rdx.Double(-2), ",-2\r\n", nil},
+		{rdx.Double(1e300), ",1e+300\r\n", nil},
+		{rdx.Float64(1.5), "+1.5\r\n", nil},
+		{rdx.Float64(-2), "+-2\r\n", nil},
+		{rdx.Float64(1e300), "+1" + strings.Repeat("0", 300) + "\r\n", nil},
+		{rdx.Float64(-1e-300), "+-0." + strings.Repeat("0", 299) + "1\r\n", nil},
+		{rdx.Float64(math.MaxFloat64), "+" + strconv.FormatFloat(math.MaxFloat64, 'f', -1, 64) + "\r\n", nil},
+		{rdx.Float64(math.SmallestNonzeroFloat64), "+" + strconv.FormatFloat(math.SmallestNonzeroFloat64, 'f', -1, 64) + "\r\n", nil},
+		{rdx.Array{rdx.Float64(1e300)}, "*1\r\n+1" + strings.Repeat("0", 300) + "\r\n", nil},
+		{rdx.Float64(math.Inf(1)), "+inf\r\n", nil},
+		{rdx.Float64(math.Inf(-1)), "+-inf\r\n", nil},
+		{rdx.Float64(math.NaN()), "+nan\r\n", nil},
+		{rdx.Array{rdx.Float64(math.Inf(1))}, "*1\r\n+inf\r\n", nil},
+
+		{rdx.Double(math.Inf(1)), ",inf\r\n", nil},
+		{rdx.Double(math.Inf(-1)), ",-inf\r\n", nil},
+		{rdx.Double(math.NaN()), ",nan\r\n", nil},
+
+		{rdx.Bool(true), "#t\r\n", nil},
+		{rdx.Bool(false), "#f\r\n", nil},
+
+		{rdx.BigNumber{}, "(0\r\n", nil},
+		{rdx.BigNumber{Int: big.NewInt(-12)}, "(-12\r\n", nil},
+		{bignum("3492890328409238509324850943850943825024385"),
+			"(3492890328409238509324850943850943825024385\r\n", nil},
+
+		{rdx.Set(nil), "~0\r\n", nil},
+		{rdx.Set{rdx.Error("\r")}, "", rdx.ErrInvalidError},
+		{rdx.Set{rdx.Int(1), rdx.BulkString("a")}, "~2\r\n:1\r\n$1\r\na\r\n", nil},
+		{rdx.Array{rdx.Set{rdx.Int(1)}}, "*1\r\n~1\r\n:1\r\n", nil},
+		{rdx.Map{{Key: rdx.Set(nil), Value: rdx.Set{nil}}}, "%1\r\n~0\r\n~1\r\n$-1\r\n", nil},
+
+		{rdx.Push(nil), ">0\r\n", nil},
+		{rdx.Push{rdx.Error("\r")}, "", rdx.ErrInvalidError},
+		{rdx.Push{rdx.BulkString("message"), rdx.Array{rdx.Int(1)}}, ">2\r\n$7\r\nmessage\r\n*1\r\n:1\r\n", nil},
+
+		{rdx.Map(nil), "%0\r\n", nil},
+		{rdx.Map{{Key: nil, Value: rdx.Error("\n")}}, "", rdx.ErrInvalidError},
+		{rdx.Map{
+			{Key: rdx.BulkString("a"), Value: rdx.Int(1)},
+			{Key: rdx.SimpleString("b"), Value: rdx.Map{{Key: rdx.Int(2), Value: nil}}},
+		}, "%2\r\n$1\r\na\r\n:1\r\n+b\r\n%1\r\n:2\r\n$-1\r\n", nil},
+		{rdx.Array{rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}, rdx.Int(3)},
+			"*2\r\n%1\r\n:1\r\n:2\r\n:3\r\n", nil},
 	}
 
 	for i, e := range table {
 		e.eval(t, i+1)
 	}
 }
+
+func TestBytes(t *testing.T) {
+	for i, m := range []rdx.Msg{
+		nil,
+		rdx.Int(-1),
+		rdx.BulkString("foo"),
+		rdx.Array{rdx.SimpleString("OK"), rdx.Set{rdx.Double(1.5)}},
+	} {
+		var want bytes.Buffer
+		rdx.Write(&want, m)
+		got, err := rdx.Bytes(m)
+		if err != nil || string(got) != want.String() {
+			t.Errorf("[%d] Bytes(%v) = %q, %v; want %q, nil", i, m, got, err, want.String())
+		}
+	}
+
+	// The result is not reused by later calls.
+	a, _ := rdx.Bytes(rdx.Int(1))
+	rdx.Bytes(rdx.Int(2))
+	if string(a) != ":1\r\n" {
+		t.Errorf("Bytes(1) = %q after reuse; want %q", a, ":1\r\n")
+	}
+
+	for _, m := range []rdx.Msg{rdx.Error("\n"), rdx.Array{rdx.Error("\r")}} {
+		if got, err := rdx.Bytes(m); err != rdx.ErrInvalidError || got != nil {
+			t.Errorf("Bytes(%q) = %q, %v; want nil, %v", m, got, err, rdx.ErrInvalidError)
+		}
+	}
+}
+
+func TestAppendMsg(t *testing.T) {
+	dst := make([]byte, 0, 64)
+	dst = append(dst, "prefix:"...)
+
+	var want bytes.Buffer
+	want.WriteString("prefix:")
+	for i, m := range []rdx.Msg{
+		nil,
+		rdx.Int(-1),
+		rdx.SimpleString("OK"),
+		rdx.Array{rdx.BulkString("SET"), rdx.String("k"), rdx.Map{{Key: rdx.Int(1), Value: rdx.Double(1.5)}}},
+		rdx.BulkString(strings.Repeat("x", 100)),
+	} {
+		var err error
+		if dst, err = rdx.AppendMsg(dst, m); err != nil {
+			t.Fatalf("[%d] AppendMsg(%v) err = %v", i, m, err)
+		}
+		rdx.Write(&want, m)
+		if string(dst) != want.String() {
+			t.Fatalf("[%d] AppendMsg(%v) = %q; want %q", i, m, dst, want.String())
+		}
+	}
+
+	for _, m := range []rdx.Msg{rdx.Error("\r\n"), rdx.Array{rdx.Int(1), rdx.Error("\n")}} {
+		got, err := rdx.AppendMsg(dst, m)
+		if err != rdx.ErrInvalidError || string(got) != want.String() {
+			t.Errorf("AppendMsg(%q) = %q, %v; want %q, %v", m, got, err, want.String(), rdx.ErrInvalidError)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	for _, c := range []struct {
+		msg  rdx.Msg
+		want string
+	}{
+		{rdx.Command("GET", "x"), "*2\r\n$3\r\nGET\r\n$1\r\nx\r\n"},
+		{rdx.CommandBytes([]byte("GET"), []byte("x")), "*2\r\n$3\r\nGET\r\n$1\r\nx\r\n"},
+		{rdx.Command("SET", "k", ""), "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$0\r\n\r\n"},
+		{rdx.Command(), "*0\r\n"},
+	} {
+		var buf bytes.Buffer
+		if _, err := rdx.Write(&buf, c.msg); err != nil {
+			t.Errorf("Write(%v) err = %v", c.msg, err)
+		} else if got := buf.String(); got != c.want {
+			t.Errorf("Write(%v) wrote %q; want %q", c.msg, got, c.want)
+		}
+	}
+}
+
+func TestWriteAll(t *testing.T) {
+	msgs := []rdx.Msg{
+		rdx.Command("SET", "k", "v"),
+		nil,
+		rdx.SimpleString("OK"),
+		rdx.Int(-1),
+		rdx.Map{{Key: rdx.BulkString("a"), Value: rdx.Double(1.5)}},
+	}
+
+	var want bytes.Buffer
+	for _, m := range msgs {
+		rdx.Write(&want, m)
+	}
+
+	var buf bytes.Buffer
+	n, err := rdx.WriteAll(&buf, msgs)
+	if err != nil || n != want.Len() || buf.String() != want.String() {
+		t.Fatalf("WriteAll() = %d, %v, wrote %q; want %d, nil, wrote %q", n, err, buf.String(), want.Len(), want.String())
+	}
+
+	// Messages before one that fails to encode are written.
+	buf.Reset()
+	n, err = rdx.WriteAll(&buf, []rdx.Msg{rdx.Int(1), rdx.Array{rdx.Int(2), rdx.Error("\r\n")}, rdx.Int(3)})
+	if err != rdx.ErrInvalidError || n != 4 || buf.String() != ":1\r\n" {
+		t.Fatalf("WriteAll() = %d, %v, wrote %q; want 4, %v, wrote %q", n, err, buf.String(), rdx.ErrInvalidError, ":1\r\n")
+	}
+
+	if n, err := rdx.WriteAll(&buf, nil); n != 0 || err != nil {
+		t.Fatalf("WriteAll(nil) = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func BenchmarkWrite_IntArray(b *testing.B) {
+	ary := make(rdx.Array, 1000)
+	for i := range ary {
+		if i%10 == 0 {
+			ary[i] = rdx.Nil
+		} else {
+			ary[i] = rdx.Int(i)
+		}
+	}
+
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		rdx.Write(&buf, ary)
+	}
+}
+
+func TestInt_WriteToAllocs(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Grow(64)
+	for _, n := range []rdx.Int{0, -1, 1<<63 - 1, -(1 << 63)} {
+		allocs := testing.AllocsPerRun(100, func() {
+			buf.Reset()
+			n.WriteTo(&buf)
+		})
+		if allocs != 0 {
+			t.Errorf("Int(%d).WriteTo allocs = %v; want 0", n, allocs)
+		}
+		if want := ":" + strconv.FormatInt(int64(n), 10) + "\r\n"; buf.String() != want {
+			t.Errorf("Int(%d).WriteTo wrote %q; want %q", n, buf.String(), want)
+		}
+	}
+}
+
+func BenchmarkWrite_Int(b *testing.B) {
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		rdx.Int(i).WriteTo(&buf)
+	}
+}
+
+// connWriter is an io.Writer that, like a net.Conn, isn't a *bytes.Buffer.
+type connWriter struct{ bytes.Buffer }
+
+func (w *connWriter) Write(p []byte) (int, error) { return w.Buffer.Write(p) }
+
+func TestWriteTo_BufferedStrings(t *testing.T) {
+	for _, size := range []int{0, 1, 10, 80, 5000} {
+		s := strings.Repeat("x", size)
+		for _, m := range []rdx.Msg{rdx.BulkString(s), rdx.SimpleString(s)} {
+			var want bytes.Buffer
+			rdx.Write(&want, m)
+
+			var conn connWriter
+			w := bufio.NewWriterSize(&conn, 16)
+			n, err := m.WriteTo(w)
+			if ferr := w.Flush(); err == nil {
+				err = ferr
+			}
+			if err != nil || n != int64(want.Len()) || conn.String() != want.String() {
+				t.Errorf("%T(%d bytes).WriteTo = %d, %v, wrote %.40q; want %d, nil, wrote %.40q",
+					m, size, n, err, conn.String(), want.Len(), want.String())
+			}
+		}
+	}
+}
+
+func BenchmarkWrite_ShortString(b *testing.B) {
+	msg := rdx.BulkString("user:1000:session")
+	conn := new(connWriter)
+	w := bufio.NewWriter(conn)
+
+	b.Run("Direct", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			conn.Reset()
+			msg.WriteTo(w)
+			w.Flush()
+		}
+	})
+
+	// A plain writer gets the frame assembled in a pooled buffer.
+	b.Run("Pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			conn.Reset()
+			msg.WriteTo(conn)
+		}
+	})
+}
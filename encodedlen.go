@@ -0,0 +1,103 @@
+package rdx
+
+import "strings"
+
+// EncodedLen returns the exact number of bytes that m occupies when encoded, as by Write or
+// Bytes. Nested messages are included, and numbers are measured in their formatted form. If m
+// cannot be encoded, EncodedLen returns the error that writing it would return. Messages of
+// types outside this package are measured by encoding them.
+func EncodedLen(m Msg) (int, error) {
+	switch m := ensure(m).(type) {
+	case nilmsg:
+		return m.estlen(), nil
+	case Error:
+		if strings.ContainsAny(string(m), "\r\n") {
+			return 0, ErrInvalidError
+		}
+		return len(m) + 3, nil
+	case Int:
+		return m.estlen(), nil
+	case String:
+		return m.estlen(), nil
+	case BulkString:
+		return m.estlen(), nil
+	case SimpleString:
+		if hasControl(string(m)) {
+			return BulkString(m).estlen(), nil
+		}
+		return len(m) + 3, nil
+	case Float64:
+		var tmp [32]byte
+		return len(appendFloat64(tmp[:0], float64(m))) + 3, nil
+	case Double:
+		var tmp [32]byte
+		return len(appendDouble(tmp[:0], float64(m))) + 3, nil
+	case BigNumber:
+		if m.Int == nil {
+			return 4, nil
+		}
+		var tmp [32]byte
+		return len(m.Int.Append(tmp[:0], 10)) + 3, nil
+	case Bool:
+		return m.estlen(), nil
+	case PreEncoded:
+		return m.estlen(), nil
+	case Array:
+		return elemsLen(m)
+	case Set:
+		return elemsLen(m)
+	case Push:
+		return elemsLen(m)
+	case Map:
+		return pairsLen(m)
+	case Attributed:
+		sz, err := pairsLen(m.Attrs)
+		if err != nil {
+			return 0, err
+		}
+		n, err := EncodedLen(m.Msg)
+		return sz + n, err
+	default:
+		var w countWriter
+		if _, err := m.WriteTo(&w); err != nil {
+			return 0, err
+		}
+		return int(w), nil
+	}
+}
+
+func elemsLen(msgs []Msg) (int, error) {
+	sz := 3 + intlen(int64(len(msgs)))
+	for _, m := range msgs {
+		n, err := EncodedLen(m)
+		if err != nil {
+			return 0, err
+		}
+		sz += n
+	}
+	return sz, nil
+}
+
+func pairsLen(pairs []Pair) (int, error) {
+	sz := 3 + intlen(int64(len(pairs)))
+	for _, p := range pairs {
+		kn, err := EncodedLen(p.Key)
+		if err != nil {
+			return 0, err
+		}
+		vn, err := EncodedLen(p.Value)
+		if err != nil {
+			return 0, err
+		}
+		sz += kn + vn
+	}
+	return sz, nil
+}
+
+// countWriter is an io.Writer that counts the bytes written to it.
+type countWriter int
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	*w += countWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,89 @@
+package rdx_test
+
+import (
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+// rawMsg is a Msg implemented outside of rdx.
+type rawMsg string
+
+func (rawMsg) Type() rdx.Type   { return rdx.TSimpleString }
+func (m rawMsg) String() string { return string(m) }
+func (m rawMsg) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, "+"+string(m)+"\r\n")
+	return int64(n), err
+}
+
+func TestEncodedLen(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890123456789012345678901234567890", 10)
+	cached, _ := rdx.Cache(rdx.Command("PING"))
+
+	msgs := []rdx.Msg{
+		nil,
+		rdx.Nil,
+		rdx.Null,
+		rdx.NilArray,
+		rdx.Error(""),
+		rdx.Error("ERR failed"),
+		rdx.Int(0),
+		rdx.Int(math.MinInt64),
+		rdx.Int(math.MaxInt64),
+		rdx.String(nil),
+		rdx.String("hello"),
+		rdx.BulkString(strings.Repeat("x", 1000)),
+		rdx.SimpleString("OK"),
+		rdx.SimpleString("a\x00b"),
+		rdx.Float64(0),
+		rdx.Float64(1.5),
+		rdx.Float64(-1e100),
+		rdx.Float64(5e-324),
+		rdx.Float64(math.Inf(-1)),
+		rdx.Double(math.Pi),
+		rdx.Double(-1e300),
+		rdx.Double(math.NaN()),
+		rdx.BigNumber{},
+		rdx.BigNumber{Int: big.NewInt(-7)},
+		rdx.BigNumber{Int: huge},
+		rdx.Bool(true),
+		rdx.Bool(false),
+		cached,
+		rawMsg("custom"),
+		rdx.Array(nil),
+		rdx.Array{rdx.Int(1), nil, rdx.Array{rdx.Float64(0.1)}},
+		rdx.Set{rdx.Double(2), rdx.Bool(true)},
+		rdx.Push{rdx.BulkString("message"), rdx.Map{{Key: rdx.Int(1), Value: rdx.Null}}},
+		rdx.Map{{Key: rdx.SimpleString("k"), Value: rdx.Set{rdx.BigNumber{Int: huge}}}},
+		rdx.Attributed{Attrs: rdx.Map{{Key: rdx.BulkString("ttl"), Value: rdx.Int(3)}}, Msg: rdx.Int(1)},
+		make(rdx.Array, 12),
+	}
+
+	for i, m := range msgs {
+		b, err := rdx.Bytes(m)
+		if err != nil {
+			t.Fatalf("[%d] Bytes(%#v) err = %v", i, m, err)
+		}
+		if n, err := rdx.EncodedLen(m); err != nil || n != len(b) {
+			t.Errorf("[%d] EncodedLen(%#v) = %d, %v; want %d, nil", i, m, n, err, len(b))
+		}
+	}
+}
+
+func TestEncodedLen_Invalid(t *testing.T) {
+	for i, m := range []rdx.Msg{
+		rdx.Error("ERR\r\nx"),
+		rdx.Array{rdx.Int(1), rdx.Error("\n")},
+		rdx.Map{{Key: rdx.Error("\r"), Value: rdx.Int(1)}},
+		rdx.Attributed{Attrs: rdx.Map{{Key: rdx.Int(1), Value: rdx.Error("\r")}}, Msg: rdx.Int(1)},
+	} {
+		_, werr := rdx.Bytes(m)
+		if n, err := rdx.EncodedLen(m); err != werr || n != 0 {
+			t.Errorf("[%d] EncodedLen(%#v) = %d, %v; want 0, %v", i, m, n, err, werr)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package rdx
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CodedError returns an Error carrying a numeric code in addition to its kind and message. Coded
+// errors follow the convention:
+//
+//	KIND [code] message
+//
+// The message is omitted, along with its separating space, if it is empty. Coded errors can be
+// parsed with ParseCodedError. As with any Error, the result cannot be written if any of its
+// parts contain a CR or LF.
+func CodedError(code int, kind, msg string) Msg {
+	s := kind + " [" + strconv.Itoa(code) + "]"
+	if msg != "" {
+		s += " " + msg
+	}
+	return Error(s)
+}
+
+// ParseCodedError parses an Error following the convention used by CodedError. If e is not a
+// coded error, ok is false.
+func ParseCodedError(e Error) (code int, kind, msg string, ok bool) {
+	s := string(e)
+	sp := strings.IndexByte(s, ' ')
+	if sp == -1 {
+		return 0, "", "", false
+	}
+	kind, s = s[:sp], s[sp+1:]
+
+	if !strings.HasPrefix(s, "[") {
+		return 0, "", "", false
+	}
+	end := strings.IndexByte(s, ']')
+	if end == -1 {
+		return 0, "", "", false
+	}
+	code, err := strconv.Atoi(s[1:end])
+	if err != nil {
+		return 0, "", "", false
+	}
+
+	switch s = s[end+1:]; {
+	case s == "":
+	case s[0] == ' ':
+		msg = s[1:]
+	default:
+		return 0, "", "", false
+	}
+
+	return code, kind, msg, true
+}
+
+// MaxSafeErrorLen is the maximum length of an Error returned by SafeError. Longer errors are
+// truncated.
+const MaxSafeErrorLen = 512
+
+// SafeError returns an Error made of kind and msg, separated by a space, that can always be
+// written. Unlike converting a string to an Error, each CR or LF in kind or msg is replaced by
+// a space, and an error longer than MaxSafeErrorLen bytes is truncated to it, without
+// splitting a UTF-8 sequence. The message is omitted, along with its separating space, if it
+// is empty. This is intended for errors that include text from an untrusted source, such as a
+// client or an upstream server.
+func SafeError(kind, msg string) Error {
+	s := kind
+	if msg != "" {
+		s += " " + msg
+	}
+	if strings.ContainsAny(s, "\r\n") {
+		// Replace bytes rather than runes so that other bytes, including invalid UTF-8, are
+		// kept as they are.
+		b := []byte(s)
+		for i, c := range b {
+			if c == '\r' || c == '\n' {
+				b[i] = ' '
+			}
+		}
+		s = string(b)
+	}
+	if len(s) > MaxSafeErrorLen {
+		end := MaxSafeErrorLen
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		s = s[:end]
+	}
+	return Error(s)
+}
+
+// WriteError writes the Error returned by SafeError(kind, msg) to w. Because the error is
+// sanitized, WriteError only fails if w does.
+func WriteError(w io.Writer, kind, msg string) (n int, err error) {
+	return Write(w, SafeError(kind, msg))
+}
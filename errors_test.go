@@ -0,0 +1,85 @@
+package rdx_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestCodedError(t *testing.T) {
+	table := []struct {
+		code int
+		kind string
+		msg  string
+		want rdx.Error
+	}{
+		{404, "NOTFOUND", "no such key", "NOTFOUND [404] no such key"},
+		{-1, "ERR", "negative code", "ERR [-1] negative code"},
+		{0, "ERR", "", "ERR [0]"},
+		{7, "ERR", "message with [brackets]", "ERR [7] message with [brackets]"},
+	}
+
+	for i, c := range table {
+		got := rdx.CodedError(c.code, c.kind, c.msg)
+		if got != c.want {
+			t.Errorf("[%d] CodedError(%d, %q, %q) = %q; want %q", i, c.code, c.kind, c.msg, got, c.want)
+			continue
+		}
+
+		code, kind, msg, ok := rdx.ParseCodedError(got.(rdx.Error))
+		if !ok || code != c.code || kind != c.kind || msg != c.msg {
+			t.Errorf("[%d] ParseCodedError(%q) = %d, %q, %q, %t; want %d, %q, %q, true",
+				i, got, code, kind, msg, ok, c.code, c.kind, c.msg)
+		}
+	}
+}
+
+func TestParseCodedError_plain(t *testing.T) {
+	for i, e := range []rdx.Error{
+		"",
+		"ERR",
+		"ERR plain message",
+		"ERR [x] not a number",
+		"ERR [12 unterminated",
+		"ERR [12]trailing",
+		"ERR  [12] double space",
+	} {
+		if code, kind, msg, ok := rdx.ParseCodedError(e); ok {
+			t.Errorf("[%d] ParseCodedError(%q) = %d, %q, %q, true; want ok=false", i, e, code, kind, msg)
+		}
+	}
+}
+
+func TestSafeError(t *testing.T) {
+	long := strings.Repeat("x", rdx.MaxSafeErrorLen)
+	table := []struct {
+		kind, msg string
+		want      rdx.Error
+	}{
+		{"ERR", "unknown command", "ERR unknown command"},
+		{"ERR", "", "ERR"},
+		{"ERR", "bad\r\nkey\n", "ERR bad  key "},
+		{"E\rRR", "x", "E RR x"},
+		// Invalid UTF-8 is kept as-is.
+		{"ERR", "bad\xff\xfe\r\nkey", "ERR bad\xff\xfe  key"},
+		{"ERR", long, rdx.Error("ERR " + long[:rdx.MaxSafeErrorLen-4])},
+		// Truncation doesn't split the 3-byte encoding of '€'.
+		{"ERR", long[:rdx.MaxSafeErrorLen-6] + "€", rdx.Error("ERR " + long[:rdx.MaxSafeErrorLen-6])},
+	}
+
+	for i, c := range table {
+		got := rdx.SafeError(c.kind, c.msg)
+		if got != c.want {
+			t.Errorf("[%d] SafeError(%q, %q) = %q; want %q", i, c.kind, c.msg, got, c.want)
+		}
+
+		var buf bytes.Buffer
+		n, err := rdx.WriteError(&buf, c.kind, c.msg)
+		if want := "-" + string(c.want) + "\r\n"; err != nil || n != len(want) || buf.String() != want {
+			t.Errorf("[%d] WriteError(%q, %q) = %d, %v, wrote %q; want %d, nil, wrote %q",
+				i, c.kind, c.msg, n, err, buf.String(), len(want), want)
+		}
+	}
+}
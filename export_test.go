@@ -0,0 +1,25 @@
+package rdx
+
+import "time"
+
+// SetClock replaces the clock r uses to compute read deadlines. It is only available to tests.
+// If setDeadline is nil, deadlines are set on the underlying connection as usual.
+func (r *Reader) SetClock(now func() time.Time, setDeadline func(t time.Time) error) {
+	r.clock = &clock{
+		now: now,
+		setDeadline: func(conn readDeadliner, t time.Time) error {
+			if setDeadline == nil {
+				return conn.SetReadDeadline(t)
+			}
+			return setDeadline(t)
+		},
+	}
+}
+
+// ScratchCap returns the capacity of the Writer's encoding buffer. It is only available to
+// tests.
+func (w *Writer) ScratchCap() int { return w.scratch.Cap() }
+
+// ScratchCap returns the capacity of the StreamingEncoder's encoding buffer. It is only
+// available to tests.
+func (e *StreamingEncoder) ScratchCap() int { return e.scratch.Cap() }
@@ -1 +1,3 @@
 module go.spiff.io/rdx
+
+go 1.21
@@ -0,0 +1,157 @@
+package rdx
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// The GoString methods of messages format them as Go expressions for %#v, such as
+// rdx.Array{rdx.Int(123), rdx.String("foo")}, so that test failures show the exact types of
+// nested messages.
+
+func (m nilmsg) GoString() string {
+	switch m {
+	case Nil:
+		return "rdx.Nil"
+	case Null:
+		return "rdx.Null"
+	case NilArray:
+		return "rdx.NilArray"
+	}
+	return "rdx.nilmsg(" + strconv.Itoa(int(m)) + ")"
+}
+
+func (i Int) GoString() string          { return string(appendGo(nil, i)) }
+func (s String) GoString() string       { return string(appendGo(nil, s)) }
+func (s BulkString) GoString() string   { return string(appendGo(nil, s)) }
+func (s SimpleString) GoString() string { return string(appendGo(nil, s)) }
+func (e Error) GoString() string        { return string(appendGo(nil, e)) }
+func (f Float64) GoString() string      { return string(appendGo(nil, f)) }
+func (d Double) GoString() string       { return string(appendGo(nil, d)) }
+func (b Bool) GoString() string         { return string(appendGo(nil, b)) }
+func (n BigNumber) GoString() string    { return string(appendGo(nil, n)) }
+func (a Array) GoString() string        { return string(appendGo(nil, a)) }
+func (s Set) GoString() string          { return string(appendGo(nil, s)) }
+func (p Push) GoString() string         { return string(appendGo(nil, p)) }
+func (m Map) GoString() string          { return string(appendGo(nil, m)) }
+func (a Attributed) GoString() string   { return string(appendGo(nil, a)) }
+
+// Format implements fmt.Formatter so that %#v uses GoString instead of the Format method of
+// the embedded big.Int, which handles all other verbs.
+func (n BigNumber) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('#') {
+		io.WriteString(s, n.GoString())
+		return
+	}
+	n.Int.Format(s, verb)
+}
+
+// appendGo appends the Go expression for m to b.
+func appendGo(b []byte, m Msg) []byte {
+	switch m := m.(type) {
+	case nil:
+		return append(b, "nil"...)
+	case nilmsg:
+		return append(b, m.GoString()...)
+	case Int:
+		b = append(b, "rdx.Int("...)
+		b = strconv.AppendInt(b, int64(m), 10)
+		return append(b, ')')
+	case String:
+		if m == nil {
+			return append(b, "rdx.String(nil)"...)
+		}
+		return appendGoString(b, "rdx.String(", string(m))
+	case BulkString:
+		return appendGoString(b, "rdx.BulkString(", string(m))
+	case SimpleString:
+		return appendGoString(b, "rdx.SimpleString(", string(m))
+	case Error:
+		return appendGoString(b, "rdx.Error(", string(m))
+	case Float64:
+		return appendGoFloat(b, "rdx.Float64(", float64(m))
+	case Double:
+		return appendGoFloat(b, "rdx.Double(", float64(m))
+	case Bool:
+		b = append(b, "rdx.Bool("...)
+		b = strconv.AppendBool(b, bool(m))
+		return append(b, ')')
+	case BigNumber:
+		if m.Int == nil {
+			return append(b, "rdx.BigNumber{}"...)
+		}
+		b = append(b, "rdx.BigNumber("...)
+		b = m.Int.Append(b, 10)
+		return append(b, ')')
+	case Array:
+		return appendGoElems(b, "rdx.Array", m)
+	case Set:
+		return appendGoElems(b, "rdx.Set", m)
+	case Push:
+		return appendGoElems(b, "rdx.Push", m)
+	case Map:
+		if m == nil {
+			return append(b, "rdx.Map(nil)"...)
+		}
+		b = append(b, "rdx.Map{"...)
+		for i, p := range m {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+			b = append(b, "{Key: "...)
+			b = appendGo(b, p.Key)
+			b = append(b, ", Value: "...)
+			b = appendGo(b, p.Value)
+			b = append(b, '}')
+		}
+		return append(b, '}')
+	case Attributed:
+		b = append(b, "rdx.Attributed{Attrs: "...)
+		b = appendGo(b, m.Attrs)
+		b = append(b, ", Msg: "...)
+		b = appendGo(b, m.Msg)
+		return append(b, '}')
+	default:
+		return append(b, fmt.Sprintf("%#v", m)...)
+	}
+}
+
+// appendGoString appends a conversion of s to a string type, escaping bytes that aren't
+// printable UTF-8 as \x sequences.
+func appendGoString(b []byte, conv, s string) []byte {
+	b = append(b, conv...)
+	b = strconv.AppendQuote(b, s)
+	return append(b, ')')
+}
+
+func appendGoFloat(b []byte, conv string, f float64) []byte {
+	b = append(b, conv...)
+	switch {
+	case math.IsInf(f, 1):
+		b = append(b, "math.Inf(1)"...)
+	case math.IsInf(f, -1):
+		b = append(b, "math.Inf(-1)"...)
+	case math.IsNaN(f):
+		b = append(b, "math.NaN()"...)
+	default:
+		b = strconv.AppendFloat(b, f, 'g', -1, 64)
+	}
+	return append(b, ')')
+}
+
+func appendGoElems(b []byte, typ string, msgs []Msg) []byte {
+	b = append(b, typ...)
+	if msgs == nil {
+		return append(b, "(nil)"...)
+	}
+	b = append(b, '{')
+	for i, m := range msgs {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = appendGo(b, m)
+	}
+	return append(b, '}')
+}
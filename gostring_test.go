@@ -0,0 +1,53 @@
+package rdx_test
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestGoString(t *testing.T) {
+	for _, c := range []struct {
+		msg  rdx.Msg
+		want string
+	}{
+		{rdx.Nil, "rdx.Nil"},
+		{rdx.Null, "rdx.Null"},
+		{rdx.NilArray, "rdx.NilArray"},
+		{rdx.Int(-123), "rdx.Int(-123)"},
+		{rdx.String(nil), "rdx.String(nil)"},
+		{rdx.String(""), `rdx.String("")`},
+		{rdx.String("\x00\xffé\r\n"), `rdx.String("\x00\xffé\r\n")`},
+		{rdx.BulkString("b"), `rdx.BulkString("b")`},
+		{rdx.SimpleString("OK"), `rdx.SimpleString("OK")`},
+		{rdx.Error("ERR x"), `rdx.Error("ERR x")`},
+		{rdx.Float64(0.5), "rdx.Float64(0.5)"},
+		{rdx.Double(math.Inf(-1)), "rdx.Double(math.Inf(-1))"},
+		{rdx.Double(math.NaN()), "rdx.Double(math.NaN())"},
+		{rdx.Bool(true), "rdx.Bool(true)"},
+		{rdx.BigNumber{}, "rdx.BigNumber{}"},
+		{rdx.BigNumber{Int: big.NewInt(-12)}, "rdx.BigNumber(-12)"},
+		{rdx.Array(nil), "rdx.Array(nil)"},
+		{rdx.Array{}, "rdx.Array{}"},
+		{
+			rdx.Array{rdx.Int(123), rdx.String("foo"), nil, rdx.Nil, rdx.Array{rdx.Set{rdx.Push(nil)}}},
+			`rdx.Array{rdx.Int(123), rdx.String("foo"), nil, rdx.Nil, rdx.Array{rdx.Set{rdx.Push(nil)}}}`,
+		},
+		{rdx.Map(nil), "rdx.Map(nil)"},
+		{
+			rdx.Map{{Key: rdx.String("k"), Value: rdx.Int(1)}, {Key: rdx.Int(2), Value: rdx.Map{}}},
+			`rdx.Map{{Key: rdx.String("k"), Value: rdx.Int(1)}, {Key: rdx.Int(2), Value: rdx.Map{}}}`,
+		},
+		{
+			rdx.Attributed{Attrs: rdx.Map{{Key: rdx.String("ttl"), Value: rdx.Int(1)}}, Msg: rdx.Nil},
+			`rdx.Attributed{Attrs: rdx.Map{{Key: rdx.String("ttl"), Value: rdx.Int(1)}}, Msg: rdx.Nil}`,
+		},
+	} {
+		if got := fmt.Sprintf("%#v", c.msg); got != c.want {
+			t.Errorf("Sprintf(%%#v) = %s; want %s", got, c.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package rdx
+
+import (
+	"bytes"
+	"errors"
+)
+
+var ErrUnbalancedQuotes = errors.New("rdx: unbalanced quotes in inline command")
+
+// inline reports whether the Reader accepts inline commands at the top level.
+func (r *Reader) inline() bool {
+	return r.AllowInline && !r.cmd
+}
+
+// isInline reports whether head begins an inline command rather than a message.
+func isInline(head []byte) bool {
+	_, ok := prefixTypes[head[0]]
+	return !ok
+}
+
+// isBlankInline reports whether head is an inline command consisting only of whitespace.
+func isBlankInline(head []byte) bool {
+	if !isInline(head) {
+		return false
+	}
+	for _, c := range head {
+		if !isInlineSpace(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isInlineSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// readInline splits the inline command in head into an Array of its arguments.
+func readInline(head []byte) (Msg, error) {
+	args, err := splitInline(head[:len(head)-2])
+	if err != nil {
+		return nil, err
+	}
+	return Array(args), nil
+}
+
+// readInlineCommandName returns the name of the inline command in head and a Reader for its
+// arguments, as by ReadCommandName.
+func (r *Reader) readInlineCommandName(head []byte) (name string, rest *Reader, err error) {
+	args, err := splitInline(head[:len(head)-2])
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, arg := range args[1:] {
+		writeElem(&buf, arg)
+	}
+	rest = r.argsReader(&buf, len(args)-1)
+	return args[0].String(), rest, nil
+}
+
+// splitInline splits line into arguments separated by whitespace, following the rules Redis
+// uses for inline commands. An argument may be enclosed in double quotes, in which case it may
+// contain whitespace and the escapes \n, \r, \t, \b, \a, \xHH, and a backslash followed by any
+// other byte to stand for that byte. An argument may also be enclosed in single quotes, in
+// which case only \' is an escape. A closing quote must be followed by whitespace or the end
+// of the line.
+func splitInline(line []byte) (args []Msg, err error) {
+	for p := 0; ; {
+		for p < len(line) && isInlineSpace(line[p]) {
+			p++
+		}
+		if p == len(line) {
+			return args, nil
+		}
+
+		var (
+			arg   []byte
+			quote byte
+		)
+		if c := line[p]; c == '"' || c == '\'' {
+			quote = c
+			p++
+		}
+
+		for ; ; p++ {
+			if p == len(line) {
+				if quote != 0 {
+					return nil, ErrUnbalancedQuotes
+				}
+				break
+			}
+
+			c := line[p]
+			if quote == 0 {
+				if isInlineSpace(c) {
+					break
+				}
+				arg = append(arg, c)
+				continue
+			}
+
+			if c == quote {
+				// The closing quote must end the argument.
+				if p+1 < len(line) && !isInlineSpace(line[p+1]) {
+					return nil, ErrUnbalancedQuotes
+				}
+				p++
+				break
+			} else if c != '\\' || p+1 == len(line) {
+				arg = append(arg, c)
+				continue
+			}
+
+			next := line[p+1]
+			if quote == '\'' {
+				if next == '\'' {
+					p++
+					c = next
+				}
+				arg = append(arg, c)
+				continue
+			}
+
+			p++
+			switch next {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'a':
+				c = '\a'
+			case 'x':
+				if p+2 < len(line) && isHex(line[p+1]) && isHex(line[p+2]) {
+					c = unhex(line[p+1])<<4 | unhex(line[p+2])
+					p += 2
+				} else {
+					c = next
+				}
+			default:
+				c = next
+			}
+			arg = append(arg, c)
+		}
+
+		if arg == nil {
+			arg = []byte{}
+		}
+		args = append(args, String(arg))
+	}
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	default:
+		return c - 'a' + 10
+	}
+}
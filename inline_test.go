@@ -0,0 +1,117 @@
+package rdx_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func strs(ss ...string) rdx.Array {
+	ary := make(rdx.Array, len(ss))
+	for i, s := range ss {
+		if s == "" {
+			ary[i] = rdx.String{} // as decoded
+		} else {
+			ary[i] = rdx.String(s)
+		}
+	}
+	return ary
+}
+
+func TestReader_AllowInline(t *testing.T) {
+	table := []struct {
+		in   string
+		want rdx.Msg
+		err  error
+	}{
+		{"PING\r\n", strs("PING"), nil},
+		{"SET foo bar\r\n", strs("SET", "foo", "bar"), nil},
+		{"  SET \t foo   bar  \r\n", strs("SET", "foo", "bar"), nil},
+		{"\r\n  \r\nPING\r\n", strs("PING"), nil},
+		{`SET "foo bar" "a\"b\\c"` + "\r\n", strs("SET", "foo bar", `a"b\c`), nil},
+		{`ECHO "\x41\x4a\n\t" "\xZZ" ""` + "\r\n", strs("ECHO", "AJ\n\t", "xZZ", ""), nil},
+		{`ECHO 'it\'s "quoted"' 'a\nb'` + "\r\n", strs("ECHO", `it's "quoted"`, `a\nb`), nil},
+		{`ECHO a"b"` + "\r\n", strs("ECHO", `a"b"`), nil},
+		{`ECHO "foo` + "\r\n", nil, rdx.ErrUnbalancedQuotes},
+		{`ECHO 'foo` + "\r\n", nil, rdx.ErrUnbalancedQuotes},
+		{`ECHO "foo"bar` + "\r\n", nil, rdx.ErrUnbalancedQuotes},
+
+		// Known prefixes are still read as messages.
+		{"+OK\r\n", rdx.String("OK"), nil},
+		{"*1\r\n$4\r\nPING\r\n", strs("PING"), nil},
+		// Inline commands are only accepted at the top level.
+		{"*1\r\nPING\r\n", nil, rdx.InvalidPrefixError('P')},
+	}
+
+	for i, c := range table {
+		r := rdx.NewReader(strings.NewReader(c.in))
+		r.AllowInline = true
+		msg, err := r.Read()
+		if err != c.err {
+			t.Errorf("[%d] Read(%q) err = %v; want %v", i, c.in, err, c.err)
+		}
+		if !reflect.DeepEqual(msg, c.want) {
+			t.Errorf("[%d] Read(%q) = %#v; want %#v", i, c.in, msg, c.want)
+		}
+	}
+
+	// Inline commands are rejected by default.
+	if _, err := rdx.NewReader(strings.NewReader("PING\r\n")).Read(); err != rdx.InvalidPrefixError('P') {
+		t.Errorf("Read() err = %v; want %v", err, rdx.InvalidPrefixError('P'))
+	}
+}
+
+func TestReader_AllowInline_ReadCommandName(t *testing.T) {
+	r := rdx.NewReader(strings.NewReader("SET k \"a b\"\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"))
+	r.AllowInline = true
+
+	for _, want := range []rdx.Array{strs("SET", "k", "a b"), strs("GET", "k")} {
+		name, rest, err := r.ReadCommandName()
+		if err != nil || name != want[0].String() {
+			t.Fatalf("ReadCommandName() = %q, _, %v; want %q, _, nil", name, err, want[0])
+		}
+		var args rdx.Array
+		for {
+			arg, err := rest.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("rest.Read() err = %v", err)
+			}
+			args = append(args, arg)
+		}
+		if !reflect.DeepEqual(args, want[1:]) {
+			t.Fatalf("args = %#v; want %#v", args, want[1:])
+		}
+	}
+}
+
+func TestReader_AllowInline_ReadCommandName_options(t *testing.T) {
+	// The arguments of an inline command are read with the options of r.
+	r := rdx.NewReader(strings.NewReader("SET k abc\r\n"))
+	r.AllowInline = true
+	r.MaxBulkSize = 2
+
+	_, rest, err := r.ReadCommandName()
+	if err != nil {
+		t.Fatalf("ReadCommandName() err = %v", err)
+	}
+	if msg, err := rest.Read(); err != nil || !rdx.Equal(msg, rdx.String("k")) {
+		t.Fatalf("rest.Read() = %v, %v; want k, nil", msg, err)
+	}
+	if _, err := rest.Read(); err != rdx.ErrBulkTooLarge {
+		t.Fatalf("rest.Read() err = %v; want %v", err, rdx.ErrBulkTooLarge)
+	}
+}
+
+func TestReader_AllowInline_attributes(t *testing.T) {
+	// An attribute line is not an inline command.
+	r := rdx.NewReader(strings.NewReader("|1\r\n+k\r\n+v\r\n*1\r\n$4\r\nPING\r\n"))
+	r.AllowInline = true
+	if name, _, err := r.ReadCommandName(); err != rdx.ErrNotCommand {
+		t.Fatalf("ReadCommandName() = %q, _, %v; want \"\", _, %v", name, err, rdx.ErrNotCommand)
+	}
+}
@@ -0,0 +1,184 @@
+package rdx
+
+import (
+	"reflect"
+	"sort"
+)
+
+// UnsupportedTypeError is returned by Marshal when it encounters a value of a type it cannot
+// convert to a Msg.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "rdx: unsupported type: " + e.Type.String()
+}
+
+// DefaultMaxMarshalDepth is the maximum depth of a value converted by Marshal, and by a
+// Marshaler whose MaxDepth is zero.
+const DefaultMaxMarshalDepth = 1000
+
+// Marshaler converts Go values to messages, as by Marshal, with options. The zero value is
+// ready to use and has the same behavior as Marshal. A Marshaler may be used concurrently.
+type Marshaler struct {
+	// MaxDepth is the maximum number of slices, arrays, maps, pointers, and interfaces that
+	// Marshal follows to reach a value. It stops Marshal from recursing forever on cyclic data,
+	// such as a map that contains itself. If MaxDepth is zero, DefaultMaxMarshalDepth is used.
+	// If it is less than zero, the depth is not limited.
+	MaxDepth int
+}
+
+// MarshalDepthError is returned by Marshal when a value is nested deeper than the maximum
+// depth. Type is the type of the value that exceeded it. It matches ErrMaxDepthExceeded when
+// compared with errors.Is.
+type MarshalDepthError struct {
+	Type reflect.Type
+}
+
+func (e *MarshalDepthError) Error() string {
+	return "rdx: value of type " + e.Type.String() + " exceeds the maximum marshal depth"
+}
+
+func (e *MarshalDepthError) Is(target error) bool { return target == ErrMaxDepthExceeded }
+
+var (
+	msgType   = reflect.TypeOf((*Msg)(nil)).Elem()
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// Marshal converts a Go value to a Msg. Values are converted according to the first of the
+// following rules that applies:
+//
+//   - nil, nil pointers, nil interfaces, nil slices, and nil maps become Nil.
+//   - Values implementing Msg are returned as-is.
+//   - []byte (and other byte slices) become a String.
+//   - Strings become a BulkString.
+//   - Booleans become a Bool.
+//   - Signed and unsigned integers become an Int. Unsigned integers greater than the maximum
+//     int64 return ErrIntRange.
+//   - Floats become a Float64.
+//   - Pointers and interfaces are converted using the value they point to or contain.
+//   - Slices and arrays become an Array of their converted elements.
+//   - Maps become a Map of their converted keys and values. Pairs are sorted by the string
+//     form of their converted keys so that the result is deterministic.
+//
+// Any other type returns an *UnsupportedTypeError. A value nested deeper than
+// DefaultMaxMarshalDepth returns a *MarshalDepthError; use a Marshaler to change the limit.
+func Marshal(v interface{}) (Msg, error) {
+	return Marshaler{}.Marshal(v)
+}
+
+// Marshal converts a Go value to a Msg, as described by the Marshal function, subject to the
+// Marshaler's options.
+func (m Marshaler) Marshal(v interface{}) (Msg, error) {
+	if v == nil {
+		return Nil, nil
+	}
+	maxDepth := m.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxMarshalDepth
+	}
+	return marshalValue(reflect.ValueOf(v), 0, maxDepth)
+}
+
+// marshalValue converts v, found at the given depth, to a Msg. If maxDepth is less than zero,
+// the depth is not limited.
+func marshalValue(v reflect.Value, depth, maxDepth int) (Msg, error) {
+	if maxDepth >= 0 && depth > maxDepth {
+		return nil, &MarshalDepthError{Type: v.Type()}
+	}
+
+	kind := v.Kind()
+	if kind != reflect.Ptr && kind != reflect.Interface && v.Type().Implements(msgType) {
+		return v.Interface().(Msg), nil
+	}
+
+	switch kind {
+	case reflect.String:
+		return BulkString(v.String()), nil
+	case reflect.Bool:
+		return Bool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > 1<<63-1 {
+			return nil, ErrIntRange
+		}
+		return Int(u), nil
+	case reflect.Float32, reflect.Float64:
+		return Float64(v.Float()), nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return Nil, nil
+		} else if kind == reflect.Ptr && v.Type().Implements(msgType) && !v.Elem().Type().Implements(msgType) {
+			// Msg implemented with pointer receivers.
+			return v.Interface().(Msg), nil
+		}
+		return marshalValue(v.Elem(), depth+1, maxDepth)
+	case reflect.Slice:
+		if v.IsNil() {
+			return Nil, nil
+		} else if v.Type().Elem().Kind() == reflect.Uint8 {
+			return String(v.Bytes()), nil
+		}
+		return marshalArray(v, depth, maxDepth)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return String(b), nil
+		}
+		return marshalArray(v, depth, maxDepth)
+	case reflect.Map:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		return marshalMap(v, depth, maxDepth)
+	}
+
+	return nil, &UnsupportedTypeError{Type: v.Type()}
+}
+
+func marshalArray(v reflect.Value, depth, maxDepth int) (Msg, error) {
+	n := v.Len()
+	if n == 0 {
+		return Array(nil), nil
+	}
+
+	ary := make(Array, n)
+	for i := range ary {
+		m, err := marshalValue(v.Index(i), depth+1, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		ary[i] = m
+	}
+	return ary, nil
+}
+
+func marshalMap(v reflect.Value, depth, maxDepth int) (Msg, error) {
+	if v.Len() == 0 {
+		return Map(nil), nil
+	}
+
+	m := make(Map, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := marshalValue(iter.Key(), depth+1, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		val, err := marshalValue(iter.Value(), depth+1, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, Pair{Key: key, Value: val})
+	}
+
+	sort.Slice(m, func(i, j int) bool {
+		return m[i].Key.String() < m[j].Key.String()
+	})
+	return m, nil
+}
@@ -0,0 +1,176 @@
+package rdx_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+type marshalString string
+
+func TestMarshal(t *testing.T) {
+	var (
+		nilInt   *int
+		nilMsg   rdx.Msg
+		intVal   = 5
+		arrayMsg = rdx.Array{rdx.Int(1)}
+	)
+
+	table := []struct {
+		in   interface{}
+		want rdx.Msg
+	}{
+		{nil, rdx.Nil},
+		{nilInt, rdx.Nil},
+		{&nilMsg, rdx.Nil},
+		{[]string(nil), rdx.Nil},
+		{map[string]int(nil), rdx.Nil},
+
+		{rdx.Int(1), rdx.Int(1)},
+		{rdx.SimpleString("OK"), rdx.SimpleString("OK")},
+		{&arrayMsg, arrayMsg},
+
+		{"foo", rdx.BulkString("foo")},
+		{marshalString("bar"), rdx.BulkString("bar")},
+		{[]byte("baz"), rdx.String("baz")},
+		{[3]byte{'a', 'b', 'c'}, rdx.String("abc")},
+		{true, rdx.Bool(true)},
+		{false, rdx.Bool(false)},
+		{int8(-8), rdx.Int(-8)},
+		{uint32(32), rdx.Int(32)},
+		{int64(-1 << 63), rdx.Int(-1 << 63)},
+		{uint64(1<<63 - 1), rdx.Int(1<<63 - 1)},
+		{1.5, rdx.Float64(1.5)},
+		{float32(0.25), rdx.Float64(0.25)},
+		{&intVal, rdx.Int(5)},
+
+		{[]string{}, rdx.Array(nil)},
+		{[]string{"a", "b"}, rdx.Array{rdx.BulkString("a"), rdx.BulkString("b")}},
+		{[2]int{1, 2}, rdx.Array{rdx.Int(1), rdx.Int(2)}},
+		{[]interface{}{1, "a", nil, []int{2}}, rdx.Array{rdx.Int(1), rdx.BulkString("a"), rdx.Nil, rdx.Array{rdx.Int(2)}}},
+
+		{map[string]int{}, rdx.Map(nil)},
+		{map[string]interface{}{"b": 2, "a": []string{"x"}, "c": nil}, rdx.Map{
+			{Key: rdx.BulkString("a"), Value: rdx.Array{rdx.BulkString("x")}},
+			{Key: rdx.BulkString("b"), Value: rdx.Int(2)},
+			{Key: rdx.BulkString("c"), Value: rdx.Nil},
+		}},
+		{map[int]bool{2: true, 1: false}, rdx.Map{
+			{Key: rdx.Int(1), Value: rdx.Bool(false)},
+			{Key: rdx.Int(2), Value: rdx.Bool(true)},
+		}},
+	}
+
+	for i, c := range table {
+		got, err := rdx.Marshal(c.in)
+		if err != nil {
+			t.Errorf("[%d] Marshal(%#v) err = %v", i, c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%d] Marshal(%#v) = %#v; want %#v", i, c.in, got, c.want)
+		}
+	}
+}
+
+func TestMarshal_errors(t *testing.T) {
+	table := []struct {
+		in  interface{}
+		err error
+	}{
+		{uint64(1 << 63), rdx.ErrIntRange},
+		{struct{}{}, &rdx.UnsupportedTypeError{Type: reflect.TypeOf(struct{}{})}},
+		{[]interface{}{1, make(chan int)}, &rdx.UnsupportedTypeError{Type: reflect.TypeOf(make(chan int))}},
+		{map[string]func(){"f": func() {}}, &rdx.UnsupportedTypeError{Type: reflect.TypeOf(func() {})}},
+	}
+
+	for i, c := range table {
+		got, err := rdx.Marshal(c.in)
+		if got != nil || !reflect.DeepEqual(err, c.err) {
+			t.Errorf("[%d] Marshal(%#v) = %v, %v; want nil, %v", i, c.in, got, err, c.err)
+		}
+	}
+}
+
+func TestMarshal_deep(t *testing.T) {
+	t.Parallel()
+
+	// Alternate slices, maps, and struct messages around a leaf.
+	var (
+		in   interface{} = rdx.Attributed{Attrs: rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}, Msg: rdx.Int(3)}
+		want rdx.Msg     = in.(rdx.Msg)
+	)
+	for i := 0; i < 100; i++ {
+		switch i % 3 {
+		case 0:
+			in = []interface{}{i, in}
+			want = rdx.Array{rdx.Int(i), want}
+		case 1:
+			in = map[string]interface{}{"k": in, "n": nil}
+			want = rdx.Map{{Key: rdx.BulkString("k"), Value: want}, {Key: rdx.BulkString("n"), Value: rdx.Nil}}
+		case 2:
+			prev := in
+			in = []interface{}{rdx.BigNumber{}, &prev}
+			want = rdx.Array{rdx.BigNumber{}, want}
+		}
+	}
+
+	got, err := rdx.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() err = %v", err)
+	} else if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Marshal() = %v; want %v", got, want)
+	}
+
+	if _, err := (rdx.Marshaler{MaxDepth: 50}).Marshal(in); !errors.Is(err, rdx.ErrMaxDepthExceeded) {
+		t.Fatalf("Marshal() err = %v with MaxDepth=50; want ErrMaxDepthExceeded", err)
+	}
+
+	// The depth counts each slice, array, map, pointer, and interface followed to a value.
+	three := [][][]int{{{1}}}
+	m := rdx.Marshaler{MaxDepth: 3}
+	if got, err := m.Marshal(&three); err == nil {
+		t.Errorf("Marshal(&%v) = %v; want error", three, got)
+	} else if want := (&rdx.MarshalDepthError{Type: reflect.TypeOf(0)}); !reflect.DeepEqual(err, want) {
+		t.Errorf("Marshal(&%v) err = %v; want %v", three, err, want)
+	}
+	if _, err := m.Marshal(three); err != nil {
+		t.Errorf("Marshal(%v) err = %v", three, err)
+	}
+
+	// A negative MaxDepth doesn't limit the depth.
+	deeper := in
+	for i := 0; i < rdx.DefaultMaxMarshalDepth; i++ {
+		deeper = []interface{}{deeper}
+	}
+	if _, err := rdx.Marshal(deeper); !errors.Is(err, rdx.ErrMaxDepthExceeded) {
+		t.Errorf("Marshal() err = %v past DefaultMaxMarshalDepth; want ErrMaxDepthExceeded", err)
+	}
+	if _, err := (rdx.Marshaler{MaxDepth: -1}).Marshal(deeper); err != nil {
+		t.Errorf("Marshal() err = %v with MaxDepth=-1", err)
+	}
+}
+
+func TestMarshal_cyclic(t *testing.T) {
+	t.Parallel()
+
+	m := map[string]interface{}{"a": 1}
+	m["self"] = m
+
+	var p interface{}
+	p = &p
+
+	s := []interface{}{1}
+	s = append(s, s)
+	s[1] = s
+
+	for _, in := range []interface{}{m, p, s} {
+		got, err := rdx.Marshal(in)
+		var derr *rdx.MarshalDepthError
+		if got != nil || !errors.As(err, &derr) || !errors.Is(err, rdx.ErrMaxDepthExceeded) {
+			t.Errorf("Marshal(%T) = %v, %v; want nil, *MarshalDepthError", in, got, err)
+		}
+	}
+}
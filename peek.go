@@ -0,0 +1,127 @@
+package rdx
+
+import (
+	"bufio"
+	"io"
+)
+
+// prefixTypes maps message prefixes to the type of message they begin.
+var prefixTypes = map[byte]Type{
+	'+': TSimpleString,
+	'-': TError,
+	':': TInt,
+	'$': TBulkString,
+	'*': TArray,
+	'%': TMap,
+	'~': TSet,
+	'>': TPush,
+	',': TDouble,
+	'(': TBigNumber,
+	'_': TNil,
+	'#': TBool,
+	'|': TAttribute,
+}
+
+// PeekType returns the type of the next message without consuming any of it. The type is
+// determined by the message's prefix alone, so nil bulk strings and arrays are reported as
+// TBulkString and TArray, respectively, and simple strings as TSimpleString even though they
+// are read as String. Attributes are reported as TAttribute, although Read returns them with
+// the message that follows as an Attributed of that message's type. If the prefix is not
+// recognized, or is not allowed by the Reader's Protocol, PeekType returns an
+// InvalidPrefixError.
+//
+// If the Reader's underlying reader cannot unread bytes (i.e., it is neither a bufio.Reader
+// nor an io.ByteScanner), PeekType wraps it in a bufio.Reader.
+func (r *Reader) PeekType() (Type, error) {
+	if r.unread != nil {
+		return r.unread.Type(), nil
+	}
+
+	c, err := r.peekPrefix()
+	if err != nil {
+		return 0, err
+	}
+
+	typ, ok := prefixTypes[c]
+	if !ok || !r.allowPrefix(c) {
+		return 0, InvalidPrefixError(c)
+	}
+	return typ, nil
+}
+
+// peekPrefix returns the prefix of the next message without consuming it, waiting for it as
+// Read would.
+func (r *Reader) peekPrefix() (byte, error) {
+	if r.stream != nil {
+		return 0, ErrStreamNotDrained
+	} else if r.cmd && r.args == 0 {
+		return 0, io.EOF
+	}
+
+	r.unwrap()
+	if !r.cmd {
+		if err := r.startTimeout(); err != nil {
+			return 0, err
+		}
+	}
+	for {
+		if err := r.awaitFirstByte(); err != nil {
+			return 0, err
+		} else if skipped, err := r.skipNilCRLF(); err != nil {
+			return 0, err
+		} else if !skipped {
+			break
+		}
+	}
+	return r.peekByte()
+}
+
+// peekByte returns the next byte of the underlying reader without consuming it.
+func (r *Reader) peekByte() (byte, error) {
+	switch br := r.src.r.(type) {
+	case *prependReader:
+		if len(br.buf) > 0 {
+			return br.buf[0], nil
+		}
+		r.src.r = br.r
+		return r.peekByte()
+	case *bufio.Reader:
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		return b[0], nil
+	case io.ByteScanner:
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		return c, br.UnreadByte()
+	default:
+		r.buf = bufio.NewReader(r.src.r)
+		r.src.r = r.buf
+		return r.peekByte()
+	}
+}
+
+// More reports whether another message may be read from r. It returns false once the stream
+// has ended cleanly at a message boundary, and true if at least one byte of another message is
+// available. If peeking fails with an error other than io.EOF, More returns true so that the
+// error is reported by the next Read. More does not consume any bytes.
+//
+// More blocks until a byte is available or the stream ends. FirstByteTimeout does not apply
+// to More.
+func (r *Reader) More() bool {
+	if r.stream != nil || r.unread != nil {
+		return true
+	} else if r.cmd && r.args == 0 {
+		return false
+	}
+
+	r.unwrap()
+	if _, err := r.skipNilCRLF(); err != nil {
+		return true
+	}
+	_, err := r.peekByte()
+	return err != io.EOF
+}
@@ -0,0 +1,120 @@
+package rdx_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+// byteReader implements the methods NewReader requires to use a reader directly, but cannot
+// unread bytes.
+type byteReader struct {
+	r *strings.Reader
+}
+
+func (b byteReader) Read(p []byte) (int, error) { return b.r.Read(p) }
+func (b byteReader) ReadByte() (byte, error)    { return b.r.ReadByte() }
+
+func (b byteReader) ReadBytes(delim byte) (line []byte, err error) {
+	for {
+		c, err := b.r.ReadByte()
+		if err != nil {
+			return line, err
+		}
+		line = append(line, c)
+		if c == delim {
+			return line, nil
+		}
+	}
+}
+
+func TestReader_PeekType(t *testing.T) {
+	const stream = "+OK\r\n-ERR\r\n:1\r\n$-1\r\n*0\r\n%0\r\n~0\r\n>0\r\n,1\r\n(1\r\n_\r\n"
+	want := []rdx.Type{
+		rdx.TSimpleString, rdx.TError, rdx.TInt, rdx.TBulkString, rdx.TArray,
+		rdx.TMap, rdx.TSet, rdx.TPush, rdx.TDouble, rdx.TBigNumber, rdx.TNil,
+	}
+
+	for _, src := range []io.Reader{
+		bytes.NewBufferString(stream),
+		strings.NewReader(stream),
+		byteReader{strings.NewReader(stream)},
+	} {
+		r := newRESP3Reader(src)
+		for i, typ := range want {
+			// Peeking repeatedly doesn't advance the stream.
+			for j := 0; j < 2; j++ {
+				if got, err := r.PeekType(); err != nil || got != typ {
+					t.Fatalf("%T [%d] PeekType() = %x, %v; want %x, nil", src, i, got, err, typ)
+				}
+			}
+			msg, err := r.Read()
+			if err != nil {
+				t.Fatalf("%T [%d] Read() err = %v", src, i, err)
+			} else if !rdx.IsA(msg, typ|rdx.TNil|rdx.TString) {
+				t.Fatalf("%T [%d] Read() = %#v; want type %x", src, i, msg, typ)
+			}
+		}
+
+		if _, err := r.PeekType(); err != io.EOF {
+			t.Fatalf("%T PeekType() err = %v; want EOF", src, err)
+		}
+	}
+
+	r := rdx.NewReader(strings.NewReader("@\r\n"))
+	if _, err := r.PeekType(); err != rdx.InvalidPrefixError('@') {
+		t.Fatalf("PeekType() err = %v; want %v", err, rdx.InvalidPrefixError('@'))
+	}
+
+	// Attributes are peeked as such, but read along with the message they annotate.
+	for _, inline := range []bool{false, true} {
+		r = newRESP3Reader(strings.NewReader("|1\r\n+a\r\n:1\r\n:2\r\n"))
+		r.AllowInline = inline
+		if got, err := r.PeekType(); err != nil || got != rdx.TAttribute {
+			t.Fatalf("PeekType() = %v, %v; want %v, nil", got, err, rdx.TAttribute)
+		}
+		want := rdx.Attributed{Attrs: rdx.Map{{Key: rdx.String("a"), Value: rdx.Int(1)}}, Msg: rdx.Int(2)}
+		if msg, err := r.Read(); err != nil || !reflect.DeepEqual(msg, want) {
+			t.Fatalf("Read() = %#v, %v; want %#v, nil", msg, err, want)
+		} else if msg.Type() != rdx.TInt {
+			t.Fatalf("Read().Type() = %v; want %v", msg.Type(), rdx.TInt)
+		}
+	}
+}
+
+func TestReader_More(t *testing.T) {
+	const stream = ":1\r\n*2\r\n+a\r\n+b\r\n"
+	for _, src := range []io.Reader{
+		bytes.NewBufferString(stream),
+		strings.NewReader(stream),
+		byteReader{strings.NewReader(stream)},
+	} {
+		r := rdx.NewReader(src)
+		var n int
+		for r.More() {
+			if _, err := r.Read(); err != nil {
+				t.Fatalf("%T Read() err = %v", src, err)
+			}
+			n++
+		}
+		if n != 2 {
+			t.Fatalf("%T read %d messages; want 2", src, n)
+		}
+	}
+
+	if rdx.NewReader(strings.NewReader("")).More() {
+		t.Fatal("More() = true for an empty stream; want false")
+	}
+
+	// Partial messages are reported so that Read can return the error.
+	r := rdx.NewReader(strings.NewReader(":1"))
+	if !r.More() {
+		t.Fatal("More() = false for a partial message; want true")
+	} else if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("Read() err = %v; want EOF", err)
+	}
+}
@@ -0,0 +1,83 @@
+package rdx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var ErrNoPendingReplies = errors.New("rdx: no replies are pending")
+
+// Pipeline queues commands to be sent together and tracks the replies still owed for them, so
+// that replies are received in the order their commands were sent. The zero value is an empty
+// Pipeline ready to use.
+//
+// A typical use sends a batch of commands, flushes them to the connection, and then receives
+// one reply per command:
+//
+//	var p rdx.Pipeline
+//	p.Send("SET", "k", "v")
+//	p.Send("GET", "k")
+//	if err := p.Flush(conn); err != nil { ... }
+//	for p.Pending() > 0 {
+//		reply, err := p.Receive(r)
+//		...
+//	}
+type Pipeline struct {
+	buf     bytes.Buffer
+	queued  int // commands in buf
+	pending int // commands flushed without a received reply
+}
+
+// Send queues a command, encoded as by WriteCommand, to be written by the next Flush.
+func (p *Pipeline) Send(args ...string) {
+	WriteCommand(&p.buf, args...)
+	p.queued++
+}
+
+// Flush writes all queued commands to w in a single call to Write, after which their replies
+// can be received. If the write fails, it is unknown which commands reached the server, so
+// the connection should be closed and the Pipeline Reset before it is used again.
+func (p *Pipeline) Flush(w io.Writer) error {
+	if p.queued == 0 {
+		return nil
+	}
+	if _, err := p.buf.WriteTo(w); err != nil {
+		return err
+	}
+	p.pending += p.queued
+	p.queued = 0
+	return nil
+}
+
+// Receive reads the reply to the oldest flushed command from r, as by Read. Error replies are
+// returned as messages. If no flushed command is awaiting a reply, Receive returns
+// ErrNoPendingReplies without reading from r. A reply is only counted as received if it was
+// read successfully.
+func (p *Pipeline) Receive(r *Reader) (Msg, error) {
+	if p.pending == 0 {
+		return nil, ErrNoPendingReplies
+	}
+	msg, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	p.pending--
+	return msg, nil
+}
+
+// Pending returns the number of flushed commands whose replies haven't been received.
+func (p *Pipeline) Pending() int {
+	return p.pending
+}
+
+// Queued returns the number of commands sent since the last Flush.
+func (p *Pipeline) Queued() int {
+	return p.queued
+}
+
+// Reset discards all queued commands and forgets any pending replies.
+func (p *Pipeline) Reset() {
+	p.buf.Reset()
+	p.queued, p.pending = 0, 0
+}
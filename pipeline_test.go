@@ -0,0 +1,94 @@
+package rdx_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestPipeline(t *testing.T) {
+	var p rdx.Pipeline
+	var conn countWriter
+
+	if err := p.Flush(&conn); err != nil || conn.writes != 0 {
+		t.Fatalf("Flush() of empty pipeline = %v with %d writes; want nil with 0 writes", err, conn.writes)
+	}
+
+	p.Send("SET", "k", "v")
+	p.Send("GET", "k")
+	p.Send("INCR", "k")
+	if q, n := p.Queued(), p.Pending(); q != 3 || n != 0 {
+		t.Fatalf("Queued(), Pending() = %d, %d; want 3, 0", q, n)
+	}
+
+	r := rdx.NewReader(strings.NewReader("+OK\r\n$1\r\nv\r\n-ERR not an integer\r\n"))
+	if _, err := p.Receive(r); err != rdx.ErrNoPendingReplies {
+		t.Fatalf("Receive() before Flush err = %v; want %v", err, rdx.ErrNoPendingReplies)
+	}
+
+	if err := p.Flush(&conn); err != nil {
+		t.Fatalf("Flush() err = %v", err)
+	}
+	if conn.writes != 1 {
+		t.Fatalf("Flush() made %d writes; want 1", conn.writes)
+	}
+	var want bytes.Buffer
+	rdx.WriteCommand(&want, "SET", "k", "v")
+	rdx.WriteCommand(&want, "GET", "k")
+	rdx.WriteCommand(&want, "INCR", "k")
+	if conn.String() != want.String() {
+		t.Fatalf("Flush() wrote %q; want %q", conn.String(), want.String())
+	}
+	if q, n := p.Queued(), p.Pending(); q != 0 || n != 3 {
+		t.Fatalf("Queued(), Pending() = %d, %d; want 0, 3", q, n)
+	}
+
+	var replies []rdx.Msg
+	for p.Pending() > 0 {
+		msg, err := p.Receive(r)
+		if err != nil {
+			t.Fatalf("Receive() err = %v", err)
+		}
+		replies = append(replies, msg)
+	}
+	wantReplies := []rdx.Msg{rdx.String("OK"), rdx.String("v"), rdx.Error("ERR not an integer")}
+	if !reflect.DeepEqual(replies, wantReplies) {
+		t.Fatalf("replies = %v; want %v", replies, wantReplies)
+	}
+	if _, err := p.Receive(r); err != rdx.ErrNoPendingReplies {
+		t.Fatalf("extra Receive() err = %v; want %v", err, rdx.ErrNoPendingReplies)
+	}
+
+	// A failed read doesn't count as a reply.
+	p.Send("PING")
+	p.Flush(io.Discard)
+	if _, err := p.Receive(r); err != io.EOF || p.Pending() != 1 {
+		t.Fatalf("Receive() = %v with %d pending; want EOF with 1 pending", err, p.Pending())
+	}
+
+	p.Reset()
+	if q, n := p.Queued(), p.Pending(); q != 0 || n != 0 {
+		t.Fatalf("after Reset: Queued(), Pending() = %d, %d; want 0, 0", q, n)
+	}
+}
+
+func TestPipeline_FlushError(t *testing.T) {
+	var p rdx.Pipeline
+	p.Send("PING")
+	errWrite := errors.New("write failed")
+	if err := p.Flush(failWriter{errWrite}); err != errWrite {
+		t.Fatalf("Flush() err = %v; want %v", err, errWrite)
+	}
+	if p.Pending() != 0 {
+		t.Fatalf("Pending() = %d after failed Flush; want 0", p.Pending())
+	}
+}
+
+type failWriter struct{ err error }
+
+func (w failWriter) Write([]byte) (int, error) { return 0, w.err }
@@ -0,0 +1,75 @@
+package rdx
+
+import "errors"
+
+var ErrReservedPrefix = errors.New("rdx: prefix is reserved by RESP")
+
+// PrefixFunc reads a message beginning with a prefix registered with RegisterPrefix. head is
+// the message's header line, including its prefix and CRLF, and must not be retained. The
+// rest of the message, if any, can be read from r using ReadPayload and Read: calls to Read
+// made by a PrefixFunc read nested messages, as if the message were an aggregate.
+type PrefixFunc func(head []byte, r *Reader) (Msg, error)
+
+// reservedPrefixes is the set of prefixes defined by RESP2 and RESP3, including those of
+// streamed aggregates and strings.
+var reservedPrefixes = [256]bool{
+	'+': true, '-': true, ':': true, '$': true, '*': true,
+	'%': true, '~': true, '>': true, ',': true, '(': true, '_': true, '#': true,
+	'|': true, '=': true, '!': true, ';': true, '.': true,
+}
+
+// RegisterPrefix sets fn as the function that reads messages beginning with the prefix b,
+// allowing a Reader to decode types from an extension of RESP. If fn is nil, the prefix is
+// unregistered. Prefixes defined by RESP2 or RESP3 cannot be registered, and return
+// ErrReservedPrefix. Registered prefixes are kept by Reset and shared with Readers returned by
+// ReadCommandName. A registered prefix takes precedence over inline commands (see
+// AllowInline), but PeekType still reports an InvalidPrefixError for it.
+func (r *Reader) RegisterPrefix(b byte, fn PrefixFunc) error {
+	if reservedPrefixes[b] || b == '\r' || b == '\n' {
+		return ErrReservedPrefix
+	}
+	if fn == nil {
+		delete(r.prefixes, b)
+		return nil
+	}
+	if r.prefixes == nil {
+		r.prefixes = make(map[byte]PrefixFunc)
+	}
+	r.prefixes[b] = fn
+	return nil
+}
+
+// readCustom reads a message using the PrefixFunc registered for head's prefix. It reports
+// false if there is none.
+func (r *Reader) readCustom(head []byte) (Msg, bool, error) {
+	fn := r.prefixes[head[0]]
+	if fn == nil {
+		return nil, false, nil
+	}
+	r.inCustom++
+	defer func() { r.inCustom-- }()
+	m, err := fn(head, r)
+	if err == nil && m == nil {
+		m = Nil
+	}
+	return m, true, err
+}
+
+// ReadPayload reads n bytes followed by a CRLF, as in the payload of a bulk string, and
+// returns the n bytes. It is intended for use by a PrefixFunc. MaxBulkSize and MaxMessageSize
+// apply to n.
+func (r *Reader) ReadPayload(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrNegativeLength
+	} else if n > maxBulkLen || r.MaxBulkSize > 0 && n > r.MaxBulkSize {
+		return nil, ErrBulkTooLarge
+	} else if err := r.checkSize(int64(n) + 2); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, n)
+	if _, err := r.readStringInto(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
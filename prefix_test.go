@@ -0,0 +1,86 @@
+package rdx_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_RegisterPrefix(t *testing.T) {
+	var z bytes.Buffer
+	zw := gzip.NewWriter(&z)
+	zw.Write([]byte("hello"))
+	zw.Close()
+
+	// @<len> is a gzipped blob, and ^<n> is a pair of a blob name and n nested messages.
+	stream := "@" + strconv.Itoa(z.Len()) + "\r\n" + z.String() + "\r\n" +
+		"^2\r\n+a\r\n:1\r\n:2\r\n" +
+		"*1\r\n@x\r\n" +
+		"+ok\r\n"
+
+	r := rdx.NewReader(strings.NewReader(stream))
+	err := r.RegisterPrefix('@', func(head []byte, r *rdx.Reader) (rdx.Msg, error) {
+		n, err := strconv.Atoi(string(head[1 : len(head)-2]))
+		if err != nil {
+			return nil, rdx.ErrInvalidLength
+		}
+		p, err := r.ReadPayload(n)
+		if err != nil {
+			return nil, err
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(p))
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(zr)
+		return rdx.String(b), err
+	})
+	if err != nil {
+		t.Fatalf("RegisterPrefix('@') err = %v", err)
+	}
+	err = r.RegisterPrefix('^', func(head []byte, r *rdx.Reader) (rdx.Msg, error) {
+		var ary rdx.Array
+		for i := 0; i < int(head[1]-'0')+1; i++ {
+			m, err := r.Read()
+			if err != nil {
+				return nil, err
+			}
+			ary = append(ary, m)
+		}
+		return ary, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterPrefix('^') err = %v", err)
+	}
+
+	if m, err := r.Read(); err != nil || !rdx.Equal(m, rdx.String("hello")) {
+		t.Fatalf("Read() = %v, %v; want hello, nil", m, err)
+	}
+	want := rdx.Array{rdx.String("a"), rdx.Int(1), rdx.Int(2)}
+	if m, err := r.Read(); err != nil || !reflect.DeepEqual(m, want) {
+		t.Fatalf("Read() = %v, %v; want %v, nil", m, err, want)
+	}
+	if m, err := r.Read(); err != rdx.ErrInvalidLength {
+		t.Fatalf("Read() = %v, %v; want nil, %v", m, err, rdx.ErrInvalidLength)
+	}
+
+	r.Reset(strings.NewReader("^0\r\n+b\r\n"))
+	if err := r.RegisterPrefix('^', nil); err != nil {
+		t.Fatalf("RegisterPrefix('^', nil) err = %v", err)
+	}
+	if _, err := r.Read(); err != rdx.InvalidPrefixError('^') {
+		t.Fatalf("Read() err = %v; want %v", err, rdx.InvalidPrefixError('^'))
+	}
+
+	for _, c := range []byte("+-:$*%~>,(_#|=!;.\r\n") {
+		if err := r.RegisterPrefix(c, func([]byte, *rdx.Reader) (rdx.Msg, error) { return rdx.Nil, nil }); err != rdx.ErrReservedPrefix {
+			t.Errorf("RegisterPrefix(%q) err = %v; want %v", c, err, rdx.ErrReservedPrefix)
+		}
+	}
+}
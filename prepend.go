@@ -0,0 +1,67 @@
+package rdx
+
+// Prepend pushes b back onto the stream so that the next read sees the bytes of b before any
+// bytes remaining in the underlying reader. This is intended for protocol transitions where
+// the caller has already read part of the RESP stream into its own buffer. b is copied, so the
+// caller may reuse it.
+func (r *Reader) Prepend(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+
+	if p, ok := r.src.r.(*prependReader); ok {
+		p.buf = append(append(make([]byte, 0, len(b)+len(p.buf)), b...), p.buf...)
+		return
+	}
+	r.src.r = &prependReader{buf: append([]byte(nil), b...), r: r.src.r}
+}
+
+// unwrap removes a drained prependReader from the Reader.
+func (r *Reader) unwrap() {
+	if p, ok := r.src.r.(*prependReader); ok && len(p.buf) == 0 {
+		r.src.r = p.r
+	}
+}
+
+// prependReader is a bytesReader that reads from buf before reading from r.
+type prependReader struct {
+	buf []byte
+	r   bytesReader
+}
+
+func (p *prependReader) Read(b []byte) (int, error) {
+	if len(p.buf) == 0 {
+		return p.r.Read(b)
+	}
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+func (p *prependReader) ReadByte() (byte, error) {
+	if len(p.buf) == 0 {
+		return p.r.ReadByte()
+	}
+	c := p.buf[0]
+	p.buf = p.buf[1:]
+	return c, nil
+}
+
+func (p *prependReader) ReadBytes(delim byte) ([]byte, error) {
+	if len(p.buf) == 0 {
+		return p.r.ReadBytes(delim)
+	}
+
+	for i, c := range p.buf {
+		if c == delim {
+			line := append([]byte(nil), p.buf[:i+1]...)
+			p.buf = p.buf[i+1:]
+			return line, nil
+		}
+	}
+
+	line := append([]byte(nil), p.buf...)
+	p.buf = nil
+	rest, err := p.r.ReadBytes(delim)
+	return append(line, rest...), err
+}
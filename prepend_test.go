@@ -0,0 +1,49 @@
+package rdx_test
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_Prepend(t *testing.T) {
+	// Simulate a caller that over-read part of the stream after a banner line.
+	src := bufio.NewReader(strings.NewReader("BANNER\r\n*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n:2\r\n"))
+	if line, err := src.ReadString('\n'); err != nil || line != "BANNER\r\n" {
+		t.Fatalf("ReadString() = %q, %v", line, err)
+	}
+	over := make([]byte, 12) // "*2\r\n$3\r\nfoo\r"
+	if _, err := io.ReadFull(src, over); err != nil {
+		t.Fatal(err)
+	}
+
+	r := rdx.NewReader(src)
+	r.Prepend(over[8:])
+	r.Prepend(over[:8])
+	over[0] = 'x' // Prepend copies its input.
+
+	if typ, err := r.PeekType(); err != nil || typ != rdx.TArray {
+		t.Fatalf("PeekType() = %x, %v; want %x, nil", typ, err, rdx.TArray)
+	}
+
+	msg, err := r.Read()
+	if want := (rdx.Array{rdx.String("foo"), rdx.String("bar")}); err != nil || !reflect.DeepEqual(msg, want) {
+		t.Fatalf("Read() = %v, %v; want %v, nil", msg, err, want)
+	}
+	if msg, err := r.Read(); err != nil || msg != rdx.Int(2) {
+		t.Fatalf("Read() = %v, %v; want 2, nil", msg, err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("Read() err = %v; want EOF", err)
+	}
+
+	// Prepended bytes can form a message on their own.
+	r.Prepend([]byte("+OK\r\n"))
+	if msg, err := r.Read(); err != nil || msg.String() != "OK" {
+		t.Fatalf("Read() = %v, %v; want OK, nil", msg, err)
+	}
+}
@@ -0,0 +1,246 @@
+package rdx
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// Protocol is a version of the RESP protocol. New Readers and Writers use RESP2.
+type Protocol int
+
+const (
+	// RESP2 restricts a Reader to RESP2 messages and a Writer to RESP2 types.
+	RESP2 Protocol = 2
+	// RESP3 allows a Reader or Writer to use all message types, and makes a Writer use the
+	// RESP3 forms of nulls and floats.
+	RESP3 Protocol = 3
+)
+
+// ErrRESP3Only is returned by a Writer in RESP2 mode when asked to write a message that can only
+// be encoded in RESP3, such as a Map, Set, Push, Double, BigNumber, or Bool.
+var ErrRESP3Only = errors.New("rdx: message type requires RESP3")
+
+// resp3Prefixes is the set of message prefixes introduced by RESP3.
+var resp3Prefixes = [256]bool{
+	'%': true,
+	'~': true,
+	',': true,
+	'#': true,
+	'(': true,
+	'=': true,
+	'>': true,
+	'_': true,
+	'|': true,
+}
+
+// SetProtocol sets the protocol version the Reader accepts. The default is RESP2, in which
+// messages beginning with a RESP3-only prefix are rejected with an InvalidPrefixError by both
+// Read and PeekType, and streamed aggregates are rejected with ErrInvalidLength. Set RESP3 to
+// read messages of both versions.
+func (r *Reader) SetProtocol(p Protocol) {
+	r.proto = p
+}
+
+// Protocol returns the protocol version of the Reader.
+func (r *Reader) Protocol() Protocol {
+	if r.proto != RESP3 {
+		return RESP2
+	}
+	return RESP3
+}
+
+// DetectProtocol sets the Reader's protocol from the next message without consuming it, as a
+// server accepting both RESP2 and RESP3 clients might do with the first message of a
+// connection. If the message begins with a RESP3-only prefix, the protocol is set to RESP3;
+// otherwise, it is set to RESP2. DetectProtocol returns the protocol that was set. If the next
+// message cannot be peeked, DetectProtocol returns the error and the protocol is unchanged.
+//
+// Only the prefix is examined, so a RESP2-framed HELLO 3 command is detected as RESP2. Servers
+// that negotiate the protocol with HELLO should call SetProtocol once it is handled.
+func (r *Reader) DetectProtocol() (Protocol, error) {
+	var resp3 bool
+	if r.unread != nil {
+		_, _, err := resp2Msg(r.unread)
+		resp3 = err != nil
+	} else {
+		c, err := r.peekPrefix()
+		if err != nil {
+			return 0, err
+		}
+		resp3 = resp3Prefixes[c]
+	}
+
+	r.proto = RESP2
+	if resp3 {
+		r.proto = RESP3
+	}
+	return r.proto, nil
+}
+
+// allowPrefix reports whether the Reader's protocol accepts messages beginning with c.
+func (r *Reader) allowPrefix(c byte) bool {
+	return r.proto == RESP3 || !resp3Prefixes[c]
+}
+
+// SetProtocol sets the protocol version the Writer encodes messages for. The default is
+// RESP2, in which writing a Map, Set, Push, Double, BigNumber, or Bool, or an aggregate
+// containing one, returns ErrRESP3Only and nothing is written. Null is written as a RESP2 nil bulk string, and the
+// attributes of an Attributed are dropped. A PreEncoded message can't be converted, so it is
+// rejected with ErrRESP3Only if its encoding uses any RESP3 type, including Null.
+//
+// In RESP3, all nil messages (Nil, Null, and NilArray) are written as a RESP3 null and Float64
+// is written as a Double, including when nested in aggregates or attributes. PreEncoded
+// messages are written as they were encoded.
+func (w *Writer) SetProtocol(p Protocol) {
+	w.proto = p
+}
+
+// Protocol returns the protocol version of the Writer.
+func (w *Writer) Protocol() Protocol {
+	if w.proto != RESP3 {
+		return RESP2
+	}
+	return RESP3
+}
+
+// resp2Msg returns m as it must be written in RESP2, or ErrRESP3Only if m cannot be. If m is
+// replaced, resp2Msg also returns true. Aggregates are only copied if one of their elements is
+// replaced.
+func resp2Msg(m Msg) (Msg, bool, error) {
+	switch m := ensure(m).(type) {
+	case nilmsg:
+		if m == Null {
+			return Nil, true, nil
+		}
+		return m, false, nil
+	case Array:
+		var elems Array
+		for i, elem := range m {
+			conv, replaced, err := resp2Msg(elem)
+			if err != nil {
+				return nil, false, err
+			}
+			if replaced && elems == nil {
+				elems = append(make(Array, 0, len(m)), m[:i]...)
+			}
+			if elems != nil {
+				elems = append(elems, conv)
+			}
+		}
+		if elems == nil {
+			return m, false, nil
+		}
+		return elems, true, nil
+	case Attributed:
+		// Attributes are optional metadata, so they're dropped rather than rejected.
+		conv, _, err := resp2Msg(m.Msg)
+		return conv, true, err
+	case PreEncoded:
+		if resp3Wire(m.wire) {
+			return nil, false, ErrRESP3Only
+		}
+		return m, false, nil
+	case Map, Set, Push, Double, BigNumber, Bool:
+		return nil, false, ErrRESP3Only
+	default:
+		return m, false, nil
+	}
+}
+
+// resp3Wire reports whether the encoded messages in b use any RESP3 type. It walks each line of
+// b, skipping the payloads of bulk strings, and checks its prefix.
+func resp3Wire(b []byte) bool {
+	for len(b) > 0 {
+		c := b[0]
+		if resp3Prefixes[c] {
+			return true
+		}
+		end := bytes.Index(b, crlf)
+		if end < 0 {
+			return false
+		}
+		line := b[1:end]
+		b = b[end+2:]
+		if (c == '$' || c == '*') && len(line) > 0 && line[0] == '?' {
+			// Streamed strings and aggregates.
+			return true
+		} else if c != '$' {
+			continue
+		}
+		if n, err := strconv.Atoi(string(line)); err == nil && n >= 0 {
+			b = b[min(len(b), n+2):]
+		}
+	}
+	return false
+}
+
+// resp3Msg returns m as it must be written in RESP3: nil messages become Null and Float64
+// becomes Double. If m is replaced, resp3Msg also returns true. Aggregates are only copied if
+// one of their elements is replaced.
+func resp3Msg(m Msg) (Msg, bool) {
+	switch m := ensure(m).(type) {
+	case nilmsg:
+		return Null, m != Null
+	case Float64:
+		return Double(m), true
+	case Array:
+		if elems, ok := resp3Elems(m); ok {
+			return Array(elems), true
+		}
+	case Set:
+		if elems, ok := resp3Elems(m); ok {
+			return Set(elems), true
+		}
+	case Push:
+		if elems, ok := resp3Elems(m); ok {
+			return Push(elems), true
+		}
+	case Map:
+		if pairs, ok := resp3Pairs(m); ok {
+			return Map(pairs), true
+		}
+	case Attributed:
+		attrs, attrsOK := resp3Pairs(m.Attrs)
+		msg, msgOK := resp3Msg(m.Msg)
+		if attrsOK || msgOK {
+			if !attrsOK {
+				attrs = m.Attrs
+			}
+			return Attributed{Attrs: attrs, Msg: msg}, true
+		}
+	}
+	return m, false
+}
+
+// resp3Elems returns a copy of elems converted by resp3Msg, or false if no element needs to be
+// converted.
+func resp3Elems(elems []Msg) ([]Msg, bool) {
+	var conv []Msg
+	for i, elem := range elems {
+		m, replaced := resp3Msg(elem)
+		if replaced && conv == nil {
+			conv = append(make([]Msg, 0, len(elems)), elems[:i]...)
+		}
+		if conv != nil {
+			conv = append(conv, m)
+		}
+	}
+	return conv, conv != nil
+}
+
+// resp3Pairs is the same as resp3Elems, but for the keys and values of pairs.
+func resp3Pairs(pairs []Pair) ([]Pair, bool) {
+	var conv []Pair
+	for i, p := range pairs {
+		key, keyReplaced := resp3Msg(p.Key)
+		val, valReplaced := resp3Msg(p.Value)
+		if (keyReplaced || valReplaced) && conv == nil {
+			conv = append(make([]Pair, 0, len(pairs)), pairs[:i]...)
+		}
+		if conv != nil {
+			conv = append(conv, Pair{Key: key, Value: val})
+		}
+	}
+	return conv, conv != nil
+}
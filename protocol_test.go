@@ -0,0 +1,255 @@
+package rdx_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_SetProtocol(t *testing.T) {
+	resp3 := []string{
+		"%1\r\n+a\r\n:1\r\n",
+		"~1\r\n:1\r\n",
+		",1.5\r\n",
+		"#t\r\n",
+		"(1\r\n",
+		"=7\r\ntxt:abc\r\n",
+		">1\r\n:1\r\n",
+		"_\r\n",
+		"|1\r\n+a\r\n:1\r\n:2\r\n",
+		"*1\r\n_\r\n",
+	}
+
+	// Streamed aggregates are also RESP3-only.
+	r := rdx.NewReader(strings.NewReader("*?\r\n.\r\n"))
+	r.SetProtocol(rdx.RESP2)
+	if msg, err := r.Read(); err != rdx.ErrInvalidLength {
+		t.Errorf("Read() = %v, %v; want nil, %v", msg, err, rdx.ErrInvalidLength)
+	}
+
+	for _, in := range resp3 {
+		want := rdx.InvalidPrefixError(in[0])
+		if in[0] == '*' {
+			want = rdx.InvalidPrefixError('_')
+		}
+		// New Readers default to RESP2.
+		r := rdx.NewReader(strings.NewReader(in))
+		if msg, err := r.Read(); err != want {
+			t.Errorf("Read(%q) = %v, %v by default; want %v", in, msg, err, want)
+		}
+		r = rdx.NewReader(strings.NewReader(in))
+		r.SetProtocol(rdx.RESP2)
+		if msg, err := r.Read(); err != want {
+			t.Errorf("Read(%q) = %v, %v; want %v", in, msg, err, want)
+		}
+
+		if in[0] == '*' || in[0] == '=' {
+			continue
+		}
+		r = rdx.NewReader(strings.NewReader(in))
+		r.SetProtocol(rdx.RESP3)
+		if _, err := r.Read(); err != nil {
+			t.Errorf("Read(%q) err = %v in RESP3", in, err)
+		}
+	}
+
+	r = rdx.NewReader(strings.NewReader("%0\r\n"))
+	if got := r.Protocol(); got != rdx.RESP2 {
+		t.Errorf("Protocol() = %v; want %v", got, rdx.RESP2)
+	}
+	if _, err := r.PeekType(); err != rdx.InvalidPrefixError('%') {
+		t.Errorf("PeekType() err = %v; want %v", err, rdx.InvalidPrefixError('%'))
+	}
+
+	r = rdx.NewReader(strings.NewReader("*3\r\n+a\r\n$1\r\nb\r\n:1\r\n"))
+	r.SetProtocol(rdx.RESP2)
+	want := rdx.Array{rdx.String("a"), rdx.String("b"), rdx.Int(1)}
+	if msg, err := r.Read(); err != nil || !rdx.Equal(msg, want) {
+		t.Errorf("Read() = %v, %v; want %v, nil", msg, err, want)
+	}
+}
+
+func TestWriter_SetProtocol(t *testing.T) {
+	for _, c := range []struct {
+		msg  rdx.Msg
+		want string
+		err  error
+	}{
+		{msg: rdx.Null, want: "$-1\r\n"},
+		{msg: rdx.NilArray, want: "*-1\r\n"},
+		{msg: rdx.Array{rdx.Int(1), rdx.Null}, want: "*2\r\n:1\r\n$-1\r\n"},
+		{msg: rdx.Array{rdx.Array{rdx.Null}}, want: "*1\r\n*1\r\n$-1\r\n"},
+		{msg: rdx.Attributed{Attrs: rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}, Msg: rdx.Int(3)}, want: ":3\r\n"},
+		{msg: rdx.Array{rdx.Attributed{Msg: rdx.Null}}, want: "*1\r\n$-1\r\n"},
+		{msg: rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}, err: rdx.ErrRESP3Only},
+		{msg: rdx.Set{rdx.Int(1)}, err: rdx.ErrRESP3Only},
+		{msg: rdx.Push{rdx.Int(1)}, err: rdx.ErrRESP3Only},
+		{msg: rdx.Double(1.5), err: rdx.ErrRESP3Only},
+		{msg: rdx.Bool(true), err: rdx.ErrRESP3Only},
+		{msg: rdx.Array{rdx.Int(1), rdx.Array{rdx.Bool(true)}}, err: rdx.ErrRESP3Only},
+		{msg: mustCache(rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}), err: rdx.ErrRESP3Only},
+		{msg: mustCache(rdx.Array{rdx.BulkString("%1"), rdx.Null}), err: rdx.ErrRESP3Only},
+		{msg: rdx.Array{mustCache(rdx.Array{rdx.Int(1), rdx.Double(1)})}, err: rdx.ErrRESP3Only},
+		{msg: mustCache(rdx.Array{rdx.BulkString("%1\r\n~2"), rdx.Nil, rdx.Int(1)}), want: "*3\r\n$6\r\n%1\r\n~2\r\n$-1\r\n:1\r\n"},
+		{msg: rdx.Array{mustCache(rdx.Error("ERR x"))}, want: "*1\r\n-ERR x\r\n"},
+	} {
+		var buf bytes.Buffer
+		w := rdx.NewWriter(&buf)
+		w.SetProtocol(rdx.RESP2)
+		err := w.WriteMsg(c.msg)
+		if ferr := w.Flush(); ferr != nil {
+			t.Fatalf("Flush() err = %v", ferr)
+		}
+		if err != c.err {
+			t.Errorf("WriteMsg(%v) err = %v; want %v", c.msg, err, c.err)
+		} else if got := buf.String(); got != c.want {
+			t.Errorf("WriteMsg(%v) wrote %q; want %q", c.msg, got, c.want)
+		}
+	}
+
+	// The message passed to WriteMsg is not modified.
+	msg := rdx.Array{rdx.Null}
+	w := rdx.NewWriter(new(bytes.Buffer))
+	w.SetProtocol(rdx.RESP2)
+	if err := w.WriteMsg(msg); err != nil {
+		t.Fatalf("WriteMsg() err = %v", err)
+	} else if msg[0] != rdx.Null {
+		t.Errorf("WriteMsg() modified msg[0] = %#v; want Null", msg[0])
+	}
+
+	var buf bytes.Buffer
+	w = rdx.NewWriter(&buf)
+	w.SetProtocol(rdx.RESP3)
+	if err := w.WriteMsg(rdx.Bool(true)); err != nil {
+		t.Fatalf("WriteMsg() err = %v in RESP3", err)
+	}
+	w.Flush()
+	if got, want := buf.String(), "#t\r\n"; got != want {
+		t.Errorf("WriteMsg() wrote %q; want %q", got, want)
+	}
+}
+
+func TestReader_DetectProtocol(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want rdx.Protocol
+		msg  rdx.Msg
+	}{
+		{"%1\r\n+a\r\n:1\r\n", rdx.RESP3, rdx.Map{{Key: rdx.String("a"), Value: rdx.Int(1)}}},
+		{"_\r\n", rdx.RESP3, rdx.Nil},
+		{"|1\r\n+a\r\n:1\r\n:2\r\n", rdx.RESP3, rdx.Attributed{Attrs: rdx.Map{{Key: rdx.String("a"), Value: rdx.Int(1)}}, Msg: rdx.Int(2)}},
+		{"*2\r\n$5\r\nHELLO\r\n$1\r\n3\r\n", rdx.RESP2, rdx.Array{rdx.String("HELLO"), rdx.String("3")}},
+		{"$-1\r\n", rdx.RESP2, rdx.Nil},
+		{":1\r\n", rdx.RESP2, rdx.Int(1)},
+	} {
+		r := rdx.NewReader(strings.NewReader(c.in))
+		// A protocol set before detection is replaced.
+		r.SetProtocol(rdx.RESP2)
+		got, err := r.DetectProtocol()
+		if err != nil || got != c.want {
+			t.Errorf("DetectProtocol(%q) = %v, %v; want %v, nil", c.in, got, err, c.want)
+			continue
+		}
+		if p := r.Protocol(); p != c.want {
+			t.Errorf("Protocol() = %v after DetectProtocol(%q); want %v", p, c.in, c.want)
+		}
+		// The message is not consumed.
+		if msg, err := r.Read(); err != nil || !rdx.Equal(msg, c.msg) {
+			t.Errorf("Read(%q) = %v, %v; want %v, nil", c.in, msg, err, c.msg)
+		}
+	}
+
+	// A RESP2-first stream rejects later RESP3 messages.
+	r := rdx.NewReader(strings.NewReader(":1\r\n~1\r\n:1\r\n"))
+	if p, err := r.DetectProtocol(); err != nil || p != rdx.RESP2 {
+		t.Fatalf("DetectProtocol() = %v, %v; want %v, nil", p, err, rdx.RESP2)
+	}
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("Read() err = %v", err)
+	}
+	if msg, err := r.Read(); err != rdx.InvalidPrefixError('~') {
+		t.Errorf("Read() = %v, %v; want nil, %v", msg, err, rdx.InvalidPrefixError('~'))
+	}
+
+	// Errors leave the protocol unchanged.
+	r = rdx.NewReader(strings.NewReader(""))
+	r.SetProtocol(rdx.RESP3)
+	if p, err := r.DetectProtocol(); err != io.EOF || r.Protocol() != rdx.RESP3 {
+		t.Errorf("DetectProtocol() = %v, %v with protocol %v; want 0, EOF with protocol %v", p, err, r.Protocol(), rdx.RESP3)
+	}
+}
+
+func mustCache(m rdx.Msg) rdx.Msg {
+	pe, err := rdx.Cache(m)
+	if err != nil {
+		panic(err)
+	}
+	return pe
+}
+
+func TestWriter_SetProtocol_RESP3(t *testing.T) {
+	for _, c := range []struct {
+		msg  rdx.Msg
+		want string
+	}{
+		{msg: rdx.Nil, want: "_\r\n"},
+		{msg: nil, want: "_\r\n"},
+		{msg: rdx.Null, want: "_\r\n"},
+		{msg: rdx.NilArray, want: "_\r\n"},
+		{msg: rdx.Float64(1.5), want: ",1.5\r\n"},
+		{msg: rdx.Float64(math.Inf(-1)), want: ",-inf\r\n"},
+		{msg: rdx.Double(2), want: ",2\r\n"},
+		{msg: rdx.Array{rdx.Int(1), rdx.Nil, rdx.Float64(0.5)}, want: "*3\r\n:1\r\n_\r\n,0.5\r\n"},
+		{msg: rdx.Set{rdx.Nil}, want: "~1\r\n_\r\n"},
+		{msg: rdx.Push{rdx.Array{rdx.Nil}}, want: ">1\r\n*1\r\n_\r\n"},
+		{msg: rdx.Map{{Key: rdx.Float64(1), Value: rdx.Nil}}, want: "%1\r\n,1\r\n_\r\n"},
+		{
+			msg:  rdx.Attributed{Attrs: rdx.Map{{Key: rdx.Int(1), Value: rdx.Float64(2)}}, Msg: rdx.Nil},
+			want: "|1\r\n:1\r\n,2\r\n_\r\n",
+		},
+		{msg: rdx.Array{rdx.Int(1), rdx.BulkString("a")}, want: "*2\r\n:1\r\n$1\r\na\r\n"},
+		// PreEncoded messages are written as they were encoded.
+		{msg: mustCache(rdx.Nil), want: "$-1\r\n"},
+	} {
+		var buf bytes.Buffer
+		w := rdx.NewWriter(&buf)
+		w.SetProtocol(rdx.RESP3)
+		if err := w.WriteMsg(c.msg); err != nil {
+			t.Errorf("WriteMsg(%v) err = %v", c.msg, err)
+			continue
+		}
+		w.Flush()
+		if got := buf.String(); got != c.want {
+			t.Errorf("WriteMsg(%v) wrote %q; want %q", c.msg, got, c.want)
+		}
+	}
+
+	// The message passed to WriteMsg is not modified.
+	msg := rdx.Map{{Key: rdx.Int(1), Value: rdx.Nil}}
+	w := rdx.NewWriter(new(bytes.Buffer))
+	w.SetProtocol(rdx.RESP3)
+	if err := w.WriteMsg(msg); err != nil {
+		t.Fatalf("WriteMsg() err = %v", err)
+	} else if msg[0].Value != rdx.Nil {
+		t.Errorf("WriteMsg() modified msg[0].Value = %#v; want Nil", msg[0].Value)
+	}
+
+	// New Writers default to RESP2.
+	var buf bytes.Buffer
+	w = rdx.NewWriter(&buf)
+	if got := w.Protocol(); got != rdx.RESP2 {
+		t.Errorf("Protocol() = %v; want %v", got, rdx.RESP2)
+	}
+	w.WriteMsg(rdx.Array{rdx.Nil, rdx.Null, rdx.Float64(1.5)})
+	if err := w.WriteMsg(rdx.Map(nil)); err != rdx.ErrRESP3Only {
+		t.Errorf("WriteMsg(Map) err = %v by default; want %v", err, rdx.ErrRESP3Only)
+	}
+	w.Flush()
+	if got, want := buf.String(), "*3\r\n$-1\r\n$-1\r\n+1.5\r\n"; got != want {
+		t.Errorf("WriteMsg() wrote %q by default; want %q", got, want)
+	}
+}
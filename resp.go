@@ -1,10 +1,12 @@
 package rdx
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
-	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -12,10 +14,6 @@ import (
 // Type is the type of a resp message.
 type Type uint
 
-var (
-	msgNilStr = []byte("$-1\r\n\r\n")
-)
-
 // Resp message types.
 const (
 	TNil Type = 1 << iota
@@ -24,9 +22,44 @@ const (
 	TInt
 	TSimpleString
 	TBulkString
+	TMap
+	TSet
+	TDouble
+	TBigNumber
+	TPush
+	TBool
+	// TAttribute is reported by PeekType for the attributes preceding a message. No message
+	// has this type: a message read with attributes is an Attributed, which has the type of
+	// the message the attributes annotate.
+	TAttribute
 	TString = TSimpleString | TBulkString
 )
 
+var typeNames = map[Type]string{
+	TNil:          "nil",
+	TError:        "error",
+	TArray:        "array",
+	TInt:          "integer",
+	TSimpleString: "simple string",
+	TBulkString:   "bulk string",
+	TMap:          "map",
+	TSet:          "set",
+	TDouble:       "double",
+	TBigNumber:    "big number",
+	TPush:         "push",
+	TBool:         "boolean",
+	TAttribute:    "attribute",
+	TString:       "string",
+}
+
+// String returns the name of the message type, such as "bulk string".
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return "Type(" + strconv.FormatUint(uint64(t), 10) + ")"
+}
+
 // Msg is any type that can be encoded as a resp message.
 type Msg interface {
 	Type() Type
@@ -43,16 +76,39 @@ type ErrMsg interface {
 
 // ToError converts the Msg, m, to an ErrMsg if it is an ErrMsg. Otherwise, it returns nil.
 func ToError(m Msg) ErrMsg {
-	err, _ := m.(ErrMsg)
+	err, _ := StripAttrs(m).(ErrMsg)
 	return err
 }
 
+// ToArray returns the elements of m if it is an Array, Set, or Push, and whether it was one.
+// An empty aggregate returns a non-nil empty slice, while Nil and other messages return nil
+// and false.
+func ToArray(m Msg) ([]Msg, bool) {
+	elems, ok := msgElems(m)
+	if ok && elems == nil {
+		elems = []Msg{}
+	}
+	return elems, ok
+}
+
 type nilmsg int
 type Int int64
 type String []byte
 type Array []Msg
+type Set []Msg
+type Push []Msg
 type Error string
 
+// Pair is a single key/value entry of a Map.
+type Pair struct {
+	Key   Msg
+	Value Msg
+}
+
+// Map is a RESP3 map. Its pairs are kept in the order they were written or read over the
+// wire.
+type Map []Pair
+
 // Encode-specific types -- when read over the wire, these will still be treated as their
 // simplified types.
 
@@ -62,14 +118,32 @@ type BulkString string
 
 // SimpleString explicitly encodes a string as a basic string instead of a bulk string. When
 // read over the wire, all SimpleStrings are received as String to avoid type preferences on
-// strings. If the SimpleString contains the sequence "\r\n", it is automatically promoted
-// to a BulkString to avoid producing an error.
+// strings, unless the Reader's PreserveStringTypes option is set. If the SimpleString
+// contains a control character (any byte below 0x20, including CR and LF), it is
+// automatically promoted to a BulkString, since strict parsers reject control characters in
+// simple strings.
 type SimpleString string
 
 // Float64 encodes a float64 as a bulk string. This is a convenience type for skipping
-// float-to-string conversion.
+// float-to-string conversion. Infinities and NaN are encoded as "inf", "-inf", and "nan", as
+// Redis does for RESP2 float replies.
 type Float64 float64
 
+// Double is a RESP3 double. Unlike Float64, it is encoded with its own type prefix and is
+// returned by the decoder for RESP3 doubles. Infinities and NaN are encoded as inf, -inf,
+// and nan.
+type Double float64
+
+// Bool is a RESP3 boolean, encoded as #t or #f.
+type Bool bool
+
+// BigNumber is a RESP3 big number, an integer of arbitrary precision. A BigNumber with a nil
+// Int is encoded as zero. The decoder returns a BigNumber for all big numbers, including those
+// that would fit in an Int.
+type BigNumber struct {
+	*big.Int
+}
+
 // ensure returns msg if it is non-nil, otherwise it returns the Nil message.
 // This is used to ensure that no Msg interface in use is nil.
 func ensure(msg Msg) Msg {
@@ -79,14 +153,37 @@ func ensure(msg Msg) Msg {
 	return msg
 }
 
-// Nil is a Msg representing a nil value.
-const Nil nilmsg = 0
+const (
+	// Nil is a Msg representing a nil value. It is encoded as a null bulk string.
+	Nil nilmsg = 0
+	// Null is a Msg representing a nil value that is encoded as a RESP3 null. Null is never
+	// returned by the decoder, which returns Nil for all null forms, so nil messages should be
+	// checked using IsA or Type instead of comparing against Nil.
+	Null nilmsg = 1
+	// NilArray is a Msg representing a nil value that is encoded as a null array, as in the
+	// reply to an aborted EXEC. Like Null, it is never returned by the decoder.
+	NilArray nilmsg = 2
+)
 
 var (
 	ErrInvalidError     = errors.New(`rdx: error contains forbidden character`)
 	ErrInvalidSimpleStr = errors.New(`rdx: simple string contains forbidden character`)
+	ErrNilMsg           = errors.New(`rdx: message is nil`)
+	ErrWrongType        = errors.New(`rdx: message has the wrong type for conversion`)
 )
 
+// WrongTypeError is returned when a message of one type is read where another type is
+// required. It matches ErrWrongType when compared with errors.Is.
+type WrongTypeError struct {
+	Got, Want Type
+}
+
+func (e *WrongTypeError) Error() string {
+	return "rdx: got " + e.Got.String() + " message; want " + e.Want.String()
+}
+
+func (e *WrongTypeError) Is(target error) bool { return target == ErrWrongType }
+
 var _ Msg = Error("")
 
 func (e Error) Error() string  { return string(e) }
@@ -94,6 +191,39 @@ func (e Error) Type() Type     { return TError }
 func (e Error) String() string { return string(e) }
 func (e Error) estlen() int    { return 3 + len(e) }
 
+// Kind returns the first space-delimited word of e, such as "WRONGTYPE" or "MOVED", as-is. If
+// e contains no space, Kind returns all of e.
+func (e Error) Kind() string {
+	if i := strings.IndexByte(string(e), ' '); i >= 0 {
+		return string(e[:i])
+	}
+	return string(e)
+}
+
+// Message returns the rest of e following the space after its Kind, which may itself contain
+// spaces. If e contains no space, Message returns an empty string.
+func (e Error) Message() string {
+	if i := strings.IndexByte(string(e), ' '); i >= 0 {
+		return string(e[i+1:])
+	}
+	return ""
+}
+
+// Prefixed returns a new Error with kind prepended to e, separated by a space. If kind is empty,
+// e is returned unchanged, and if e is empty, the result is only kind. This is intended for
+// proxies namespacing errors received from upstream (e.g., turning "ERR msg" into
+// "PROXY ERR msg") while keeping the original text. As with any Error, WriteTo rejects the
+// result if it contains a CR or LF.
+func (e Error) Prefixed(kind string) Error {
+	switch {
+	case kind == "":
+		return e
+	case e == "":
+		return Error(kind)
+	}
+	return Error(kind + " " + string(e))
+}
+
 func (e Error) writeTo(buf *bytes.Buffer) (n int64) {
 	buf.WriteByte('-')
 	buf.WriteString(string(e))
@@ -154,15 +284,55 @@ func (s String) WriteTo(w io.Writer) (n int64, err error) {
 
 var _ Msg = Nil
 
-var nilmsgBytes = [...]byte{'$', '-', '1', '\r', '\n'}
+var (
+	nilmsgBytes      = [...]byte{'$', '-', '1', '\r', '\n'}
+	nullmsgBytes     = [...]byte{'_', '\r', '\n'}
+	nilArraymsgBytes = [...]byte{'*', '-', '1', '\r', '\n'}
+)
 
 func (nilmsg) Type() Type     { return TNil }
 func (nilmsg) String() string { return "<nil>" }
-func (nilmsg) estlen() int    { return len(nilmsgBytes) }
 
-func (nilmsg) WriteTo(w io.Writer) (n int64, err error) {
-	b := nilmsgBytes // copy
-	in, err := w.Write(b[:])
+func (m nilmsg) estlen() int {
+	switch m {
+	case Null:
+		return len(nullmsgBytes)
+	case NilArray:
+		return len(nilArraymsgBytes)
+	default:
+		return len(nilmsgBytes)
+	}
+}
+
+func (m nilmsg) writeTo(buf *bytes.Buffer) (n int64) {
+	switch m {
+	case Null:
+		buf.Write(nullmsgBytes[:])
+	case NilArray:
+		buf.Write(nilArraymsgBytes[:])
+	default:
+		buf.Write(nilmsgBytes[:])
+	}
+	return int64(m.estlen())
+}
+
+func (m nilmsg) WriteTo(w io.Writer) (n int64, err error) {
+	if buf, ok := w.(*bytes.Buffer); ok {
+		return m.writeTo(buf), nil
+	}
+
+	var in int
+	switch m {
+	case Null:
+		b := nullmsgBytes // copy
+		in, err = w.Write(b[:])
+	case NilArray:
+		b := nilArraymsgBytes // copy
+		in, err = w.Write(b[:])
+	default:
+		b := nilmsgBytes // copy
+		in, err = w.Write(b[:])
+	}
 	return int64(in), err
 }
 
@@ -174,6 +344,10 @@ func (i Int) estlen() int    { return 3 + intlen(int64(i)) }
 
 func (i Int) WriteTo(w io.Writer) (n int64, err error) {
 	i64 := int64(i)
+	if buf, ok := w.(*bytes.Buffer); ok {
+		return putint(buf, ':', i64), nil
+	}
+
 	buf := tempbuffer(i.estlen())
 	putint(buf, ':', i64)
 
@@ -191,12 +365,155 @@ var _ Msg = Array(nil)
 
 func (Array) Type() Type { return TArray }
 
-func (a Array) String() string { return fmt.Sprint([]Msg(a)) }
+// String returns a human-readable form of a for debugging, such as
+// ["GET", "key", 1, (nil), (error "ERR x"), ["nested"]]. Strings are quoted, errors and nils are
+// marked, and nested sets, pushes, and maps are shown as ~[...], >[...], and {key: value}. The
+// form is deterministic but is not the wire format.
+func (a Array) String() string { return string(appendDebug(nil, a)) }
+
+func (a Array) estlen() int { return elemsEstlen(a) }
 
-func (a Array) estlen() int {
-	sz := 3 + intlen(int64(len(a)))
+func (a Array) writeTo(buf *bytes.Buffer) (err error) {
+	return writeElems(buf, '*', a)
+}
 
-	for _, m := range a {
+func (a Array) WriteTo(w io.Writer) (n int64, err error) {
+	buf := tempbuffer(a.estlen())
+	defer putbuffer(buf)
+	if err = a.writeTo(buf); err != nil {
+		return 0, err
+	}
+
+	return buf.WriteTo(w)
+}
+
+// Append returns a with msgs appended, as by the built-in append.
+func (a Array) Append(msgs ...Msg) Array {
+	return append(a, msgs...)
+}
+
+// OrderedPairs returns the elements of a flat array of alternating keys and values, such as the
+// RESP2 reply to HGETALL, as a list of pairs in their original order. Keys may be of any type.
+// If a has an odd number of elements, ErrOddMapLength is returned.
+func (a Array) OrderedPairs() ([]Pair, error) {
+	if len(a)%2 != 0 {
+		return nil, ErrOddMapLength
+	} else if len(a) == 0 {
+		return nil, nil
+	}
+
+	pairs := make([]Pair, len(a)/2)
+	for i := range pairs {
+		pairs[i] = Pair{Key: a[i*2], Value: a[i*2+1]}
+	}
+	return pairs, nil
+}
+
+// Command returns an Array of args as BulkStrings, the form in which commands are sent to a
+// server. For example, Command("SET", key, value) encodes as a three-element array of bulk
+// strings.
+func Command(args ...string) Array {
+	cmd := make(Array, len(args))
+	for i, arg := range args {
+		cmd[i] = BulkString(arg)
+	}
+	return cmd
+}
+
+// CommandBytes returns an Array of args as Strings. It is the same as Command, but for
+// arguments that are already byte slices. The args are not copied.
+func CommandBytes(args ...[]byte) Array {
+	cmd := make(Array, len(args))
+	for i, arg := range args {
+		cmd[i] = String(arg)
+	}
+	return cmd
+}
+
+// Flatten returns a single Array of msgs with any Arrays among them, at any depth, replaced by
+// their elements. Other messages, including Nil, are kept as elements. This allows commands to
+// be built from groups of arguments, such as Flatten(Command("MSET"), keyvals).
+func Flatten(msgs ...Msg) Array {
+	flat := make(Array, 0, flatLen(msgs))
+	return appendFlat(flat, msgs)
+}
+
+func flatLen(msgs []Msg) (n int) {
+	for _, m := range msgs {
+		if a, ok := m.(Array); ok {
+			n += flatLen(a)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+func appendFlat(flat Array, msgs []Msg) Array {
+	for _, m := range msgs {
+		if a, ok := m.(Array); ok {
+			flat = appendFlat(flat, a)
+		} else {
+			flat = append(flat, m)
+		}
+	}
+	return flat
+}
+
+// appendDebug appends the human-readable form of m used by Array.String to b.
+func appendDebug(b []byte, m Msg) []byte {
+	switch m := ensure(m).(type) {
+	case String, BulkString, SimpleString:
+		return strconv.AppendQuote(b, m.String())
+	case Error:
+		b = append(b, "(error "...)
+		b = strconv.AppendQuote(b, string(m))
+		return append(b, ')')
+	case nilmsg:
+		return append(b, "(nil)"...)
+	case Array:
+		return appendDebugElems(b, "[", m)
+	case Set:
+		return appendDebugElems(b, "~[", m)
+	case Push:
+		return appendDebugElems(b, ">[", m)
+	case Map:
+		b = append(b, '{')
+		for i, p := range m {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+			b = appendDebug(b, p.Key)
+			b = append(b, ": "...)
+			b = appendDebug(b, p.Value)
+		}
+		return append(b, '}')
+	case Attributed:
+		b = append(b, '|')
+		b = appendDebug(b, m.Attrs)
+		b = append(b, ' ')
+		return appendDebug(b, m.Msg)
+	default:
+		return append(b, m.String()...)
+	}
+}
+
+func appendDebugElems(b []byte, open string, msgs []Msg) []byte {
+	b = append(b, open...)
+	for i, m := range msgs {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = appendDebug(b, m)
+	}
+	return append(b, ']')
+}
+
+// elemsEstlen returns the estimated encoded length of an aggregate of msgs.
+func elemsEstlen(msgs []Msg) int {
+	sz := 3 + intlen(int64(len(msgs)))
+
+	for _, m := range msgs {
 		m = ensure(m)
 		if em, ok := m.(estlen); ok {
 			sz += em.estlen()
@@ -206,27 +523,131 @@ func (a Array) estlen() int {
 	return sz
 }
 
-func (a Array) writeTo(buf *bytes.Buffer) (err error) {
-	putint(buf, '*', int64(len(a)))
-	for _, m := range a {
-		switch m := ensure(m).(type) {
-		case Array:
-			err = m.writeTo(buf)
-		default:
-			_, err = m.WriteTo(buf)
+// writeElems writes an aggregate header with the given prefix followed by each of msgs to buf.
+func writeElems(buf *bytes.Buffer, prefix byte, msgs []Msg) (err error) {
+	putint(buf, prefix, int64(len(msgs)))
+	for _, m := range msgs {
+		if err = writeElem(buf, m); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// writeElem writes an element of an aggregate message to buf. Aggregates are written
+// directly to buf instead of going through WriteTo to avoid acquiring a temporary buffer per
+// element.
+func writeElem(buf *bytes.Buffer, m Msg) (err error) {
+	switch m := ensure(m).(type) {
+	case Array:
+		err = m.writeTo(buf)
+	case Map:
+		err = m.writeTo(buf)
+	case Set:
+		err = m.writeTo(buf)
+	case Push:
+		err = m.writeTo(buf)
+	case Attributed:
+		err = m.writeTo(buf)
+	default:
+		_, err = m.WriteTo(buf)
+	}
+	return err
+}
+
+var _ Msg = Set(nil)
+
+func (Set) Type() Type { return TSet }
+
+// String returns a human-readable form of s for debugging, such as ~[1, "a"]. It uses the same
+// form as Array.String, which shows s the same way when s is nested in an Array.
+func (s Set) String() string { return string(appendDebug(nil, s)) }
+
+func (s Set) estlen() int { return elemsEstlen(s) }
+
+func (s Set) writeTo(buf *bytes.Buffer) (err error) {
+	return writeElems(buf, '~', s)
+}
 
-		if err != nil {
+func (s Set) WriteTo(w io.Writer) (n int64, err error) {
+	buf := tempbuffer(s.estlen())
+	defer putbuffer(buf)
+	if err = s.writeTo(buf); err != nil {
+		return 0, err
+	}
+
+	return buf.WriteTo(w)
+}
+
+var _ Msg = Push(nil)
+
+func (Push) Type() Type { return TPush }
+
+// String returns a human-readable form of p for debugging, such as >["message", "ch", "hi"]. It
+// uses the same form as Array.String.
+func (p Push) String() string { return string(appendDebug(nil, p)) }
+
+func (p Push) estlen() int { return elemsEstlen(p) }
+
+func (p Push) writeTo(buf *bytes.Buffer) (err error) {
+	return writeElems(buf, '>', p)
+}
+
+func (p Push) WriteTo(w io.Writer) (n int64, err error) {
+	buf := tempbuffer(p.estlen())
+	defer putbuffer(buf)
+	if err = p.writeTo(buf); err != nil {
+		return 0, err
+	}
+
+	return buf.WriteTo(w)
+}
+
+var _ Msg = Map(nil)
+
+func (Map) Type() Type { return TMap }
+
+// String returns a human-readable form of m for debugging, such as {"a": 1, "b": (nil)}. It
+// uses the same form as Array.String.
+func (m Map) String() string { return string(appendDebug(nil, m)) }
+
+func (m Map) estlen() int {
+	sz := 3 + intlen(int64(len(m)))
+
+	for _, p := range m {
+		if em, ok := ensure(p.Key).(estlen); ok {
+			sz += em.estlen()
+		}
+		if em, ok := ensure(p.Value).(estlen); ok {
+			sz += em.estlen()
+		}
+	}
+
+	return sz
+}
+
+func (m Map) writeTo(buf *bytes.Buffer) (err error) {
+	return writePairs(buf, '%', m)
+}
+
+// writePairs writes an aggregate of key/value pairs, such as a map or attributes.
+func writePairs(buf *bytes.Buffer, prefix byte, pairs []Pair) (err error) {
+	putint(buf, prefix, int64(len(pairs)))
+	for _, p := range pairs {
+		if err = writeElem(buf, p.Key); err != nil {
+			return err
+		}
+		if err = writeElem(buf, p.Value); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (a Array) WriteTo(w io.Writer) (n int64, err error) {
-	buf := tempbuffer(a.estlen())
+func (m Map) WriteTo(w io.Writer) (n int64, err error) {
+	buf := tempbuffer(m.estlen())
 	defer putbuffer(buf)
-	if err = a.writeTo(buf); err != nil {
+	if err = m.writeTo(buf); err != nil {
 		return 0, err
 	}
 
@@ -255,6 +676,8 @@ func (s BulkString) writeTo(buf *bytes.Buffer) (n int64, err error) {
 func (s BulkString) WriteTo(w io.Writer) (n int64, err error) {
 	if buf, ok := w.(*bytes.Buffer); ok {
 		return s.writeTo(buf)
+	} else if bw, ok := w.(*bufio.Writer); ok {
+		return writeFrame(bw, '$', true, string(s))
 	}
 
 	buf := tempbuffer(s.estlen())
@@ -266,13 +689,37 @@ func (s BulkString) WriteTo(w io.Writer) (n int64, err error) {
 	return n, err
 }
 
+// MaxAutoSimpleLen is the maximum length of a string that AutoString encodes as a SimpleString.
+const MaxAutoSimpleLen = 64
+
+// AutoString returns s as the most compact message that can safely encode it. Strings of up to
+// MaxAutoSimpleLen bytes that contain no control characters are returned as a SimpleString. All
+// other strings are returned as a BulkString.
+func AutoString(s string) Msg {
+	if len(s) <= MaxAutoSimpleLen && !hasControl(s) {
+		return SimpleString(s)
+	}
+	return BulkString(s)
+}
+
 var _ Msg = SimpleString("")
 
 func (SimpleString) Type() Type       { return TSimpleString }
 func (s SimpleString) String() string { return string(s) }
 
+// hasControl reports whether s contains a control character, which can't be encoded in a
+// simple string.
+func hasControl(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 {
+			return true
+		}
+	}
+	return false
+}
+
 func (s SimpleString) estlen() int {
-	if strings.ContainsAny(string(s), "\r\n") {
+	if hasControl(string(s)) {
 		return BulkString(s).estlen()
 	}
 	sz := len(s)
@@ -288,10 +735,12 @@ func (s SimpleString) writeTo(buf *bytes.Buffer) (n int64, err error) {
 }
 
 func (s SimpleString) WriteTo(w io.Writer) (n int64, err error) {
-	if strings.ContainsAny(string(s), "\r\n") {
+	if hasControl(string(s)) {
 		return BulkString(s).WriteTo(w)
 	} else if buf, ok := w.(*bytes.Buffer); ok {
 		return s.writeTo(buf)
+	} else if bw, ok := w.(*bufio.Writer); ok {
+		return writeFrame(bw, '+', false, string(s))
 	}
 
 	buf := tempbuffer(s.estlen())
@@ -305,27 +754,341 @@ func (s SimpleString) WriteTo(w io.Writer) (n int64, err error) {
 var _ Msg = Float64(0)
 
 func (Float64) Type() Type       { return TSimpleString }
-func (f Float64) String() string { return strconv.FormatFloat(float64(f), 'f', -1, 64) }
-func (Float64) estlen() int      { return 23 }
+func (f Float64) String() string { return string(appendFloat64(nil, float64(f))) }
+
+// estlen returns an upper bound on the encoded length of f. Because Float64 is formatted
+// without an exponent, large and small magnitudes have hundreds of digits, so the bound is
+// derived from f's binary exponent: its decimal exponent is at most |exp|*log10(2) + 1.
+func (f Float64) estlen() int {
+	const overhead = 1 + 1 + 1 + 2 // prefix, sign, decimal point, CRLF
+	const maxSig = 17              // significant digits needed to round-trip a float64
+	if math.IsInf(float64(f), 0) || math.IsNaN(float64(f)) {
+		return overhead + 3
+	}
+	_, exp := math.Frexp(float64(f))
+	if exp < 0 {
+		exp = -exp
+	}
+	return overhead + maxSig + exp*3/10 + 2
+}
 
 func (f Float64) WriteTo(w io.Writer) (n int64, err error) {
 	var tmp = [32]byte{'+'}
-	b := strconv.AppendFloat(tmp[1:], float64(f), 'f', -1, 64)
+	b := tmp[:1]
+	if sz := f.estlen(); sz > len(tmp) {
+		b = make([]byte, 1, sz)
+		b[0] = '+'
+	}
+	b = appendFloat64(b, float64(f))
+	b = append(b, "\r\n"...)
+
+	in, err := w.Write(b)
+	return int64(in), err
+}
+
+var _ Msg = BigNumber{}
+
+func (BigNumber) Type() Type { return TBigNumber }
+
+func (b BigNumber) String() string {
+	if b.Int == nil {
+		return "0"
+	}
+	return b.Int.String()
+}
+
+func (b BigNumber) estlen() int {
+	if b.Int == nil {
+		return 4
+	}
+	// Roughly log10(2) digits per bit, plus one for rounding and one for the sign.
+	return 5 + b.Int.BitLen()*3/10
+}
+
+func (b BigNumber) WriteTo(w io.Writer) (n int64, err error) {
+	buf := make([]byte, 1, b.estlen())
+	buf[0] = '('
+	if b.Int == nil {
+		buf = append(buf, '0')
+	} else {
+		buf = b.Int.Append(buf, 10)
+	}
+	buf = append(buf, "\r\n"...)
+
+	in, err := w.Write(buf)
+	return int64(in), err
+}
+
+var _ Msg = Bool(false)
+
+var (
+	boolTrueBytes  = [...]byte{'#', 't', '\r', '\n'}
+	boolFalseBytes = [...]byte{'#', 'f', '\r', '\n'}
+)
+
+func (Bool) Type() Type       { return TBool }
+func (b Bool) String() string { return strconv.FormatBool(bool(b)) }
+func (Bool) estlen() int      { return len(boolTrueBytes) }
+
+func (b Bool) WriteTo(w io.Writer) (n int64, err error) {
+	var in int
+	if b {
+		p := boolTrueBytes // copy
+		in, err = w.Write(p[:])
+	} else {
+		p := boolFalseBytes // copy
+		in, err = w.Write(p[:])
+	}
+	return int64(in), err
+}
+
+var _ Msg = Double(0)
+
+func (Double) Type() Type       { return TDouble }
+func (d Double) String() string { return string(appendDouble(nil, float64(d))) }
+func (Double) estlen() int      { return 27 }
+
+func (d Double) WriteTo(w io.Writer) (n int64, err error) {
+	var tmp = [32]byte{','}
+	b := appendDouble(tmp[:1], float64(d))
 	b = append(b, "\r\n"...)
 
 	in, err := w.Write(b)
 	return int64(in), err
 }
 
+// appendFloat64 appends the Float64 representation of f to b.
+func appendFloat64(b []byte, f float64) []byte {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return appendDouble(b, f)
+	}
+	return strconv.AppendFloat(b, f, 'f', -1, 64)
+}
+
+// appendDouble appends the RESP3 double representation of f to b.
+func appendDouble(b []byte, f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return append(b, "inf"...)
+	case math.IsInf(f, -1):
+		return append(b, "-inf"...)
+	case math.IsNaN(f):
+		return append(b, "nan"...)
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, 64)
+}
+
 func ToFloat(msg Msg) (float64, error) {
 	return strconv.ParseFloat(ensure(msg).String(), 64)
 }
 
+// ToInt converts msg to an integer. An Int is returned as-is, a BigNumber is returned if it
+// fits in an int64, and string messages are parsed as base-10 integers. ToInt(Nil) returns
+// ErrNilMsg, and an ErrMsg is returned as the error. Other messages return ErrWrongType.
+func ToInt(msg Msg) (int64, error) {
+	msg = StripAttrs(msg)
+	switch m := ensure(msg).(type) {
+	case Int:
+		return int64(m), nil
+	case BigNumber:
+		if m.Int == nil {
+			return 0, nil
+		} else if !m.IsInt64() {
+			return 0, ErrIntRange
+		}
+		return m.Int64(), nil
+	case nilmsg:
+		return 0, ErrNilMsg
+	case ErrMsg:
+		return 0, m
+	}
+	if IsA(msg, TString) {
+		return strconv.ParseInt(msg.String(), 10, 64)
+	}
+	return 0, ErrWrongType
+}
+
+// ToString converts msg to a string. String messages are returned as-is, and integers,
+// doubles, big numbers, and booleans are returned in their string form. ToString(Nil) returns
+// ErrNilMsg, and an ErrMsg is returned as the error. Aggregates return ErrWrongType.
+func ToString(msg Msg) (string, error) {
+	msg = StripAttrs(msg)
+	switch m := ensure(msg).(type) {
+	case nilmsg:
+		return "", ErrNilMsg
+	case ErrMsg:
+		return "", m
+	case Int, Double, BigNumber, Bool:
+		return m.String(), nil
+	}
+	if IsA(msg, TString) {
+		return msg.String(), nil
+	}
+	return "", ErrWrongType
+}
+
+// ToBool converts msg to a boolean. A Bool is returned as-is, an Int of 1 or 0 is true or
+// false, and the strings "true", "1", and "OK" are true while "false" and "0" are false.
+// ToBool(Nil) returns ErrNilMsg, and an ErrMsg is returned as the error. Other messages,
+// including other integers and strings, return ErrWrongType.
+func ToBool(msg Msg) (bool, error) {
+	msg = StripAttrs(msg)
+	switch m := ensure(msg).(type) {
+	case Bool:
+		return bool(m), nil
+	case Int:
+		if m == 0 || m == 1 {
+			return m == 1, nil
+		}
+		return false, ErrWrongType
+	case nilmsg:
+		return false, ErrNilMsg
+	case ErrMsg:
+		return false, m
+	}
+	if IsA(msg, TString) {
+		switch msg.String() {
+		case "true", "1", "OK":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+	}
+	return false, ErrWrongType
+}
+
+// IsNil reports whether msg is nil: a nil Msg, Nil, Null, NilArray, or a message whose Type is
+// TNil. Empty strings and aggregates are not nil.
+func IsNil(msg Msg) bool {
+	return msg == nil || msg.Type() == TNil
+}
+
 func IsA(msg Msg, typ Type) bool {
 	return ensure(msg).Type()&typ != 0 && typ != 0
 }
 
+// Equal reports whether a and b are logically equal, regardless of their Go types. Simple and
+// bulk strings (including String and Float64) are equal if their contents are. Arrays, sets,
+// pushes, and maps are equal if they are of the same type and their elements are equal in
+// order. Other messages are equal if they are of the same type and have the same string form.
+// All nil messages are equal, and a nil Msg is treated as Nil.
+func Equal(a, b Msg) bool {
+	a, b = ensure(StripAttrs(a)), ensure(StripAttrs(b))
+	ta, tb := a.Type(), b.Type()
+	if ta&TString != 0 && tb&TString != 0 {
+		return a.String() == b.String()
+	} else if ta != tb {
+		return false
+	}
+
+	switch ta {
+	case TNil:
+		return true
+	case TArray, TSet, TPush:
+		ea, oka := msgElems(a)
+		eb, okb := msgElems(b)
+		if !oka || !okb {
+			break
+		} else if len(ea) != len(eb) {
+			return false
+		}
+		for i := range ea {
+			if !Equal(ea[i], eb[i]) {
+				return false
+			}
+		}
+		return true
+	case TMap:
+		ma, oka := a.(Map)
+		mb, okb := b.(Map)
+		if !oka || !okb {
+			break
+		} else if len(ma) != len(mb) {
+			return false
+		}
+		for i := range ma {
+			if !Equal(ma[i].Key, mb[i].Key) || !Equal(ma[i].Value, mb[i].Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.String() == b.String()
+}
+
+// msgElems returns the elements of an Array, Set, or Push.
+func msgElems(m Msg) ([]Msg, bool) {
+	switch m := StripAttrs(m).(type) {
+	case Array:
+		return m, true
+	case Set:
+		return m, true
+	case Push:
+		return m, true
+	}
+	return nil, false
+}
+
+// Bytes returns the encoded form of msg. If msg cannot be encoded, Bytes returns its error.
+func Bytes(msg Msg) ([]byte, error) {
+	msg = ensure(msg)
+	var sz int
+	if em, ok := msg.(estlen); ok {
+		sz = em.estlen()
+	}
+
+	buf := tempbuffer(sz)
+	defer putbuffer(buf)
+	if err := writeElem(buf, msg); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// AppendMsg appends the encoded form of msg to dst and returns the extended slice. If msg
+// cannot be encoded, AppendMsg returns dst unchanged along with the error.
+func AppendMsg(dst []byte, msg Msg) ([]byte, error) {
+	msg = ensure(msg)
+	buf := bytes.NewBuffer(dst)
+	if em, ok := msg.(estlen); ok {
+		buf.Grow(em.estlen())
+	}
+	if err := writeElem(buf, msg); err != nil {
+		return dst, err
+	}
+	return buf.Bytes(), nil
+}
+
 func Write(w io.Writer, msg Msg) (n int, err error) {
 	in, err := ensure(msg).WriteTo(w)
 	return int(in), err
 }
+
+// WriteAll encodes msgs back-to-back into a single buffer and writes it to w in one call, such
+// as to send a pipeline of commands or replies with a single write. If a message cannot be
+// encoded, the messages before it are written and WriteAll returns the number of bytes written
+// along with the encoding error.
+func WriteAll(w io.Writer, msgs []Msg) (n int, err error) {
+	sz := 0
+	for _, msg := range msgs {
+		if em, ok := ensure(msg).(estlen); ok {
+			sz += em.estlen()
+		}
+	}
+
+	buf := tempbuffer(sz)
+	defer putbuffer(buf)
+	for _, msg := range msgs {
+		end := buf.Len()
+		if err = writeElem(buf, msg); err != nil {
+			buf.Truncate(end)
+			break
+		}
+	}
+
+	in, werr := buf.WriteTo(w)
+	if werr != nil {
+		err = werr
+	}
+	return int(in), err
+}
@@ -0,0 +1,413 @@
+package rdx_test
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestError_Prefixed(t *testing.T) {
+	table := []struct {
+		err  rdx.Error
+		kind string
+		want rdx.Error
+	}{
+		{err: "ERR unknown command", kind: "PROXY", want: "PROXY ERR unknown command"},
+		{err: "unknown command", kind: "PROXY", want: "PROXY unknown command"},
+		{err: "", kind: "PROXY", want: "PROXY"},
+		{err: "ERR unknown command", kind: "", want: "ERR unknown command"},
+		{err: "", kind: "", want: ""},
+	}
+
+	for i, c := range table {
+		if got := c.err.Prefixed(c.kind); got != c.want {
+			t.Errorf("[%d] %q.Prefixed(%q) = %q; want %q", i, c.err, c.kind, got, c.want)
+		}
+	}
+}
+
+func TestError_Kind(t *testing.T) {
+	table := []struct {
+		err           rdx.Error
+		kind, message string
+	}{
+		{"", "", ""},
+		{"ERR", "ERR", ""},
+		{"ERR ", "ERR", ""},
+		{"WRONGTYPE Operation against a key", "WRONGTYPE", "Operation against a key"},
+		{"MOVED 3999 127.0.0.1:6381", "MOVED", "3999 127.0.0.1:6381"},
+		{"ERR  two  spaces ", "ERR", " two  spaces "},
+		{" leading", "", "leading"},
+		{"err lower", "err", "lower"},
+	}
+
+	for i, c := range table {
+		if got := c.err.Kind(); got != c.kind {
+			t.Errorf("[%d] %q.Kind() = %q; want %q", i, c.err, got, c.kind)
+		}
+		if got := c.err.Message(); got != c.message {
+			t.Errorf("[%d] %q.Message() = %q; want %q", i, c.err, got, c.message)
+		}
+	}
+}
+
+func TestArray_OrderedPairs(t *testing.T) {
+	table := []struct {
+		ary  rdx.Array
+		want []rdx.Pair
+		err  error
+	}{
+		{ary: nil, want: nil},
+		{ary: rdx.Array{rdx.Int(1)}, err: rdx.ErrOddMapLength},
+		{ary: rdx.Array{rdx.String("a"), rdx.Int(1), rdx.String("b")}, err: rdx.ErrOddMapLength},
+		{
+			ary: rdx.Array{rdx.String("z"), rdx.Int(1), rdx.Int(2), rdx.Nil, rdx.String("a"), rdx.String("v")},
+			want: []rdx.Pair{
+				{Key: rdx.String("z"), Value: rdx.Int(1)},
+				{Key: rdx.Int(2), Value: rdx.Nil},
+				{Key: rdx.String("a"), Value: rdx.String("v")},
+			},
+		},
+	}
+
+	for i, c := range table {
+		got, err := c.ary.OrderedPairs()
+		if err != c.err {
+			t.Errorf("[%d] OrderedPairs() err = %v; want %v", i, err, c.err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%d] OrderedPairs() = %v; want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	keyvals := rdx.Array{
+		rdx.Array{rdx.BulkString("a"), rdx.BulkString("1")},
+		rdx.Array{rdx.BulkString("b"), rdx.Array{rdx.Int(2)}},
+	}
+	for i, c := range []struct {
+		in   []rdx.Msg
+		want rdx.Array
+	}{
+		{nil, rdx.Array{}},
+		{[]rdx.Msg{rdx.Array(nil), rdx.Array{}}, rdx.Array{}},
+		{
+			[]rdx.Msg{rdx.Command("MSET"), keyvals},
+			rdx.Array{rdx.BulkString("MSET"), rdx.BulkString("a"), rdx.BulkString("1"), rdx.BulkString("b"), rdx.Int(2)},
+		},
+		{
+			[]rdx.Msg{rdx.Nil, rdx.Array{rdx.Nil, nil}, rdx.Set{rdx.Int(1)}, rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}},
+			rdx.Array{rdx.Nil, rdx.Nil, nil, rdx.Set{rdx.Int(1)}, rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}},
+		},
+	} {
+		got := rdx.Flatten(c.in...)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%d] Flatten(%v) = %v; want %v", i, c.in, got, c.want)
+		}
+		if len(got) != cap(got) {
+			t.Errorf("[%d] Flatten(%v) has cap %d; want %d", i, c.in, cap(got), len(got))
+		}
+	}
+
+	// The input is not modified.
+	if want := (rdx.Array{rdx.BulkString("a"), rdx.BulkString("1")}); !reflect.DeepEqual(keyvals[0], want) {
+		t.Errorf("keyvals[0] = %v; want %v", keyvals[0], want)
+	}
+}
+
+func TestArray_String(t *testing.T) {
+	for _, c := range []struct {
+		msg  rdx.Array
+		want string
+	}{
+		{nil, "[]"},
+		{rdx.Array{rdx.Int(123), rdx.String("foo")}, `[123, "foo"]`},
+		{
+			rdx.Array{
+				rdx.BulkString("a b"),
+				rdx.SimpleString("OK"),
+				rdx.String("line\r\n"),
+				rdx.Nil,
+				rdx.Error("ERR x"),
+				rdx.Array{rdx.Int(-1), rdx.Array(nil)},
+			},
+			`["a b", "OK", "line\r\n", (nil), (error "ERR x"), [-1, []]]`,
+		},
+		{
+			rdx.Array{
+				rdx.Set{rdx.Int(1)},
+				rdx.Push{rdx.String("message")},
+				rdx.Map{{Key: rdx.String("k"), Value: rdx.Double(1.5)}, {Key: rdx.Int(2), Value: rdx.Bool(true)}},
+				rdx.Attributed{Attrs: rdx.Map{{Key: rdx.String("ttl"), Value: rdx.Int(10)}}, Msg: rdx.String("v")},
+				nil,
+			},
+			`[~[1], >["message"], {"k": 1.5, 2: true}, |{"ttl": 10} "v", (nil)]`,
+		},
+	} {
+		if got := c.msg.String(); got != c.want {
+			t.Errorf("String() = %s; want %s", got, c.want)
+		}
+	}
+
+	// Sets, pushes, and maps print the same at top level as when nested.
+	for _, c := range []struct {
+		msg  rdx.Msg
+		want string
+	}{
+		{rdx.Set(nil), "~[]"},
+		{rdx.Set{rdx.Int(1), rdx.String("a")}, `~[1, "a"]`},
+		{rdx.Push{rdx.String("message"), rdx.Array{rdx.Nil}}, `>["message", [(nil)]]`},
+		{rdx.Map(nil), "{}"},
+		{rdx.Map{{Key: rdx.String("k"), Value: rdx.Set{rdx.Int(1)}}, {Key: rdx.Int(2), Value: nil}}, `{"k": ~[1], 2: (nil)}`},
+	} {
+		if got := c.msg.String(); got != c.want {
+			t.Errorf("%T.String() = %s; want %s", c.msg, got, c.want)
+		}
+		if got, want := (rdx.Array{c.msg}).String(), "["+c.want+"]"; got != want {
+			t.Errorf("Array{%T}.String() = %s; want %s", c.msg, got, want)
+		}
+	}
+}
+
+func TestAutoString(t *testing.T) {
+	if _, ok := rdx.AutoString("OK").(rdx.SimpleString); !ok {
+		t.Error("AutoString(short) is not a SimpleString")
+	}
+	if _, ok := rdx.AutoString(strings.Repeat("x", 100)).(rdx.BulkString); !ok {
+		t.Error("AutoString(long) is not a BulkString")
+	}
+	if _, ok := rdx.AutoString("a\r\nb").(rdx.BulkString); !ok {
+		t.Error("AutoString(CRLF) is not a BulkString")
+	}
+	if _, ok := rdx.AutoString("a\x00b").(rdx.BulkString); !ok {
+		t.Error("AutoString(NUL) is not a BulkString")
+	}
+}
+
+func TestIsNil(t *testing.T) {
+	table := []struct {
+		msg  rdx.Msg
+		want bool
+	}{
+		{nil, true},
+		{rdx.Nil, true},
+		{rdx.Null, true},
+		{rdx.NilArray, true},
+		{rdx.Attributed{Msg: rdx.Nil}, true},
+		{rdx.String(nil), false},
+		{rdx.String{}, false},
+		{rdx.BulkString(""), false},
+		{rdx.Array(nil), false},
+		{rdx.Int(0), false},
+	}
+
+	for i, c := range table {
+		if got := rdx.IsNil(c.msg); got != c.want {
+			t.Errorf("[%d] IsNil(%#v) = %t; want %t", i, c.msg, got, c.want)
+		}
+	}
+}
+
+func TestReader_EmptyBulkNotNil(t *testing.T) {
+	const stream = "$0\r\n\r\n$?\r\n;0\r\n"
+	for _, c := range []struct {
+		name  string
+		setup func(r *rdx.Reader)
+	}{
+		{"default", func(*rdx.Reader) {}},
+		{"arena", func(r *rdx.Reader) { r.Arena = rdx.NewArena(16) }},
+	} {
+		r := newRESP3Reader(strings.NewReader(stream))
+		c.setup(r)
+		for i := 0; i < 2; i++ {
+			msg, err := r.Read()
+			if s, ok := msg.(rdx.String); err != nil || !ok || s == nil || len(s) != 0 || rdx.IsNil(msg) {
+				t.Errorf("%s: [%d] Read() = %#v, %v; want empty non-nil String, nil", c.name, i, msg, err)
+			}
+		}
+	}
+
+	r := rdx.NewReader(strings.NewReader("*1\r\n$0\r\n\r\n"))
+	msg, err := r.ReadInto(rdx.Array{rdx.String("x")})
+	if ary, ok := msg.(rdx.Array); err != nil || !ok || len(ary) != 1 || ary[0].(rdx.String) == nil {
+		t.Errorf("ReadInto() = %#v, %v; want [empty non-nil String], nil", msg, err)
+	}
+
+	r = rdx.NewReader(strings.NewReader("$0\r\n\r\n"))
+	if s, err := r.ReadBulkSniffed(func([]byte) error { return nil }); err != nil || s == nil || len(s) != 0 {
+		t.Errorf("ReadBulkSniffed() = %#v, %v; want empty non-nil String, nil", s, err)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	var nilMsg rdx.Msg
+	table := []struct {
+		a, b rdx.Msg
+		want bool
+	}{
+		{rdx.String("foo"), rdx.BulkString("foo"), true},
+		{rdx.SimpleString("foo"), rdx.String("foo"), true},
+		{rdx.Float64(1.5), rdx.BulkString("1.5"), true},
+		{rdx.String("foo"), rdx.String("bar"), false},
+		{rdx.String("1"), rdx.Int(1), false},
+		{rdx.Error("ERR"), rdx.SimpleString("ERR"), false},
+		{rdx.Error("ERR"), rdx.Error("ERR"), true},
+		{rdx.Int(1), rdx.Int(1), true},
+		{rdx.Int(1), rdx.Int(2), false},
+		{rdx.Double(1.5), rdx.Double(1.5), true},
+		{rdx.Bool(true), rdx.Bool(false), false},
+
+		{rdx.Nil, rdx.Null, true},
+		{rdx.Nil, rdx.NilArray, true},
+		{nilMsg, rdx.Nil, true},
+		{nil, nil, true},
+		{nil, rdx.String(""), false},
+
+		{rdx.Array{rdx.String("a"), rdx.Int(1)}, rdx.Array{rdx.BulkString("a"), rdx.Int(1)}, true},
+		{rdx.Array{rdx.String("a")}, rdx.Array{rdx.String("a"), rdx.Int(1)}, false},
+		{rdx.Array{nil}, rdx.Array{rdx.Nil}, true},
+		{rdx.Array(nil), rdx.Array{}, true},
+		{rdx.Array{rdx.Int(1)}, rdx.Set{rdx.Int(1)}, false},
+		{rdx.Push{rdx.Set{rdx.String("x")}}, rdx.Push{rdx.Set{rdx.SimpleString("x")}}, true},
+		{
+			rdx.Map{{Key: rdx.String("k"), Value: rdx.Int(1)}},
+			rdx.Map{{Key: rdx.BulkString("k"), Value: rdx.Int(1)}},
+			true,
+		},
+		{
+			rdx.Map{{Key: rdx.String("k"), Value: rdx.Int(1)}},
+			rdx.Map{{Key: rdx.String("k"), Value: rdx.Int(2)}},
+			false,
+		},
+	}
+
+	for i, c := range table {
+		if got := rdx.Equal(c.a, c.b); got != c.want {
+			t.Errorf("[%d] Equal(%#v, %#v) = %t; want %t", i, c.a, c.b, got, c.want)
+		}
+		if got := rdx.Equal(c.b, c.a); got != c.want {
+			t.Errorf("[%d] Equal(%#v, %#v) = %t; want %t", i, c.b, c.a, got, c.want)
+		}
+	}
+}
+
+func TestToInt(t *testing.T) {
+	table := []struct {
+		in   rdx.Msg
+		want int64
+		err  error
+	}{
+		{rdx.Int(-5), -5, nil},
+		{rdx.String("42"), 42, nil},
+		{rdx.SimpleString("-1"), -1, nil},
+		{rdx.BulkString("9223372036854775807"), 1<<63 - 1, nil},
+		{rdx.BigNumber{big.NewInt(7)}, 7, nil},
+		{bignum("99999999999999999999"), 0, rdx.ErrIntRange},
+		{rdx.Nil, 0, rdx.ErrNilMsg},
+		{nil, 0, rdx.ErrNilMsg},
+		{rdx.Error("ERR x"), 0, rdx.Error("ERR x")},
+		{rdx.Array{rdx.Int(1)}, 0, rdx.ErrWrongType},
+		{rdx.Double(1), 0, rdx.ErrWrongType},
+	}
+
+	for i, c := range table {
+		got, err := rdx.ToInt(c.in)
+		if err != c.err || got != c.want {
+			t.Errorf("[%d] ToInt(%#v) = %d, %v; want %d, %v", i, c.in, got, err, c.want, c.err)
+		}
+	}
+
+	if _, err := rdx.ToInt(rdx.String("1.5")); err == nil {
+		t.Errorf("ToInt(1.5) err = nil; want error")
+	}
+}
+
+func TestToString(t *testing.T) {
+	table := []struct {
+		in   rdx.Msg
+		want string
+		err  error
+	}{
+		{rdx.String("foo"), "foo", nil},
+		{rdx.SimpleString("OK"), "OK", nil},
+		{rdx.BulkString(""), "", nil},
+		{rdx.Int(-5), "-5", nil},
+		{rdx.Double(1.5), "1.5", nil},
+		{rdx.Bool(true), "true", nil},
+		{rdx.BigNumber{big.NewInt(7)}, "7", nil},
+		{rdx.Nil, "", rdx.ErrNilMsg},
+		{nil, "", rdx.ErrNilMsg},
+		{rdx.Error("ERR x"), "", rdx.Error("ERR x")},
+		{rdx.Array{rdx.String("a")}, "", rdx.ErrWrongType},
+		{rdx.Map(nil), "", rdx.ErrWrongType},
+	}
+
+	for i, c := range table {
+		got, err := rdx.ToString(c.in)
+		if err != c.err || got != c.want {
+			t.Errorf("[%d] ToString(%#v) = %q, %v; want %q, %v", i, c.in, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestToArray(t *testing.T) {
+	table := []struct {
+		in   rdx.Msg
+		want []rdx.Msg
+		ok   bool
+	}{
+		{rdx.Array{rdx.Int(1)}, []rdx.Msg{rdx.Int(1)}, true},
+		{rdx.Array(nil), []rdx.Msg{}, true},
+		{rdx.Array{}, []rdx.Msg{}, true},
+		{rdx.Set{rdx.Int(1)}, []rdx.Msg{rdx.Int(1)}, true},
+		{rdx.Push{rdx.String("a")}, []rdx.Msg{rdx.String("a")}, true},
+		{rdx.Nil, nil, false},
+		{nil, nil, false},
+		{rdx.String("a"), nil, false},
+		{rdx.Map{{Key: rdx.Int(1), Value: rdx.Int(2)}}, nil, false},
+	}
+
+	for i, c := range table {
+		got, ok := rdx.ToArray(c.in)
+		if ok != c.ok || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%d] ToArray(%#v) = %#v, %t; want %#v, %t", i, c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestToBool(t *testing.T) {
+	table := []struct {
+		in   rdx.Msg
+		want bool
+		err  error
+	}{
+		{rdx.Bool(true), true, nil},
+		{rdx.Bool(false), false, nil},
+		{rdx.Int(1), true, nil},
+		{rdx.Int(0), false, nil},
+		{rdx.SimpleString("OK"), true, nil},
+		{rdx.String("true"), true, nil},
+		{rdx.BulkString("1"), true, nil},
+		{rdx.String("false"), false, nil},
+		{rdx.SimpleString("0"), false, nil},
+		{rdx.Int(2), false, rdx.ErrWrongType},
+		{rdx.Int(-1), false, rdx.ErrWrongType},
+		{rdx.String("yes"), false, rdx.ErrWrongType},
+		{rdx.String("ok"), false, rdx.ErrWrongType},
+		{rdx.Array{rdx.Int(1)}, false, rdx.ErrWrongType},
+		{rdx.Nil, false, rdx.ErrNilMsg},
+		{nil, false, rdx.ErrNilMsg},
+		{rdx.Error("ERR x"), false, rdx.Error("ERR x")},
+	}
+
+	for i, c := range table {
+		got, err := rdx.ToBool(c.in)
+		if err != c.err || got != c.want {
+			t.Errorf("[%d] ToBool(%#v) = %t, %v; want %t, %v", i, c.in, got, err, c.want, c.err)
+		}
+	}
+}
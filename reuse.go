@@ -0,0 +1,103 @@
+package rdx
+
+// reuse holds the storage of a message passed to ReadInto, to be reused by the message read.
+// Arrays and strings are taken in the order they appear in the previous message, so a message
+// with the same shape as the previous one reuses all of its storage.
+type reuse struct {
+	arrays [][]Msg
+	strs   [][]byte
+	ai, si int // indices of the next array and string to take
+}
+
+// ReadInto reads the next message like Read, but reuses the storage of prev, a message
+// previously returned by r, where it can. The caller must not retain prev or any message
+// nested in it after calling ReadInto, since their contents may be overwritten by the message
+// read.
+//
+// The following storage is reused:
+//
+//   - If prev is a String, its bytes are reused for the payload of a bulk string.
+//   - If prev is an Array, its elements are reused for an array, and the Strings and Arrays
+//     nested in it are reused for the bulk strings and arrays of the message read.
+//
+// Storage is taken in the order it appears in prev and only reused if it has enough capacity
+// to hold a string's payload or an array's elements. Storage that doesn't fit is skipped, so a
+// message with the same shape as prev reuses the most. Simple strings, sets, maps, and pushes
+// are always allocated, as are strings when Arena is set and arrays when ArrayRing is set.
+func (r *Reader) ReadInto(prev Msg) (Msg, error) {
+	if prev == nil {
+		return r.Read()
+	}
+
+	if r.reuse == nil {
+		r.reuse = new(reuse)
+	}
+	r.reuse.collect(prev, r.ArrayRing <= 0)
+	defer r.reuse.reset()
+	return r.Read()
+}
+
+// collect adds the storage of m and the messages nested in it to p.
+func (p *reuse) collect(m Msg, arrays bool) {
+	switch m := m.(type) {
+	case String:
+		if cap(m) > 0 {
+			p.strs = append(p.strs, m[:0])
+		}
+	case Array:
+		if arrays && cap(m) > 0 {
+			p.arrays = append(p.arrays, m[:0])
+		}
+		for _, elem := range m {
+			p.collect(elem, arrays)
+		}
+	}
+}
+
+// reset releases the storage held by p.
+func (p *reuse) reset() {
+	for i := range p.arrays {
+		p.arrays[i] = nil
+	}
+	for i := range p.strs {
+		p.strs[i] = nil
+	}
+	p.arrays, p.strs = p.arrays[:0], p.strs[:0]
+	p.ai, p.si = 0, 0
+}
+
+// array returns the next reusable array as a slice of n elements, or nil if there is none or
+// it is too small.
+func (p *reuse) array(n int) []Msg {
+	if p == nil || p.ai >= len(p.arrays) {
+		return nil
+	}
+	a := p.arrays[p.ai]
+	p.arrays[p.ai] = nil
+	p.ai++
+	if cap(a) < n {
+		return nil
+	}
+
+	// Drop references to the previous elements beyond n.
+	a = a[:cap(a)]
+	for i := n; i < len(a); i++ {
+		a[i] = nil
+	}
+	return a[:n]
+}
+
+// str returns the next reusable string as a slice of n bytes, or nil if there is none, it is
+// too small, or n is zero.
+func (p *reuse) str(n int) []byte {
+	if p == nil || n == 0 || p.si >= len(p.strs) {
+		return nil
+	}
+	s := p.strs[p.si]
+	p.strs[p.si] = nil
+	p.si++
+	if cap(s) < n {
+		return nil
+	}
+	return s[:n]
+}
@@ -0,0 +1,75 @@
+package rdx_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_ReadInto(t *testing.T) {
+	replies := []rdx.Msg{
+		rdx.Array{rdx.String("hello"), rdx.Array{rdx.String("abc"), rdx.Int(1)}},
+		rdx.Array{rdx.String("world"), rdx.Array{rdx.String("xy"), rdx.Int(2)}},
+		rdx.Array{rdx.String("a longer string"), rdx.Int(3)},
+		rdx.String("bulk"),
+		rdx.String("size"),
+	}
+	var buf bytes.Buffer
+	for _, m := range replies {
+		rdx.Write(&buf, m)
+	}
+	r := rdx.NewReader(&buf)
+
+	read := func(prev rdx.Msg, want rdx.Msg) rdx.Msg {
+		t.Helper()
+		msg, err := r.ReadInto(prev)
+		if err != nil {
+			t.Fatalf("ReadInto() err = %v", err)
+		}
+		if !reflect.DeepEqual(msg, want) {
+			t.Fatalf("ReadInto() = %v; want %v", msg, want)
+		}
+		return msg
+	}
+	elem := func(m rdx.Msg, i ...int) rdx.Msg {
+		for _, i := range i {
+			m = m.(rdx.Array)[i]
+		}
+		return m
+	}
+	same := func(a, b rdx.Msg) bool {
+		switch a := a.(type) {
+		case rdx.Array:
+			return &a[0] == &b.(rdx.Array)[0]
+		case rdx.String:
+			return &a[0] == &b.(rdx.String)[0]
+		}
+		return false
+	}
+
+	first := read(nil, replies[0])
+	firstTop, firstStr, firstNested := elem(first), elem(first, 0), elem(first, 1, 0)
+
+	// All of a message's storage is reused by a message of the same shape.
+	second := read(first, replies[1])
+	if !same(second, firstTop) || !same(elem(second, 0), firstStr) || !same(elem(second, 1, 0), firstNested) {
+		t.Fatal("storage of a message with the same shape was not reused")
+	}
+
+	// Storage too small for a string is skipped.
+	third := read(second, replies[2])
+	if !same(third, firstTop) || same(elem(third, 0), firstStr) {
+		t.Fatal("array was not reused or short string was reused")
+	}
+
+	// Strings nested in an array are reused by a string message.
+	fourth := read(third, replies[3])
+	if !same(fourth, elem(third, 0)) {
+		t.Fatal("string nested in an array was not reused")
+	}
+	if fifth := read(fourth, replies[4]); !same(fifth, fourth) {
+		t.Fatal("string storage was not reused")
+	}
+}
@@ -0,0 +1,118 @@
+package rdx
+
+// arraySlab is a reusable backing buffer for the arrays of a single message read through a
+// Reader's ArrayRing.
+type arraySlab struct {
+	buf   []Msg
+	used  int  // number of elements allocated for the current message
+	inUse bool // true until the message using the slab is released
+}
+
+// minSlabLen is the minimum capacity of an arraySlab's buffer.
+const minSlabLen = 64
+
+// alloc returns a slice of n elements from the slab. If the slab does not have room for n
+// elements, a new buffer is allocated and the previous one is left to any arrays still
+// referencing it.
+func (s *arraySlab) alloc(n int) []Msg {
+	if cap(s.buf)-len(s.buf) < n {
+		size := cap(s.buf) * 2
+		if size < n {
+			size = n
+		}
+		if size < minSlabLen {
+			size = minSlabLen
+		}
+		s.buf = make([]Msg, 0, size)
+	}
+	s.used += n
+	off := len(s.buf)
+	s.buf = s.buf[:off+n]
+	return s.buf[off : off+n : off+n]
+}
+
+// release makes the slab available for reuse. If the message outgrew the slab's buffer, it is
+// replaced with one large enough to hold all of the message's arrays.
+func (s *arraySlab) release() {
+	if s.used > cap(s.buf) {
+		s.buf = make([]Msg, 0, s.used)
+	} else {
+		for i := range s.buf {
+			s.buf[i] = nil
+		}
+		s.buf = s.buf[:0]
+	}
+	s.used = 0
+	s.inUse = false
+}
+
+// nextSlab selects the slab to be used by the next top-level message, if ArrayRing is set and
+// a slab in the ring has been released. Slabs are tried in order starting from the one after
+// the slab last used.
+func (r *Reader) nextSlab() {
+	r.slab, r.last = nil, nil
+	if r.ArrayRing <= 0 {
+		r.ring = nil
+		return
+	}
+
+	if len(r.ring) != r.ArrayRing {
+		r.ring = make([]*arraySlab, r.ArrayRing)
+		for i := range r.ring {
+			r.ring[i] = new(arraySlab)
+		}
+		r.ringPos = 0
+	}
+	for i := range r.ring {
+		pos := (r.ringPos + i) % len(r.ring)
+		if s := r.ring[pos]; !s.inUse {
+			r.slab, r.ringPos = s, pos
+			return
+		}
+	}
+}
+
+// allocArray returns a slice of length elements for an array from the current message's slab.
+// It returns nil if there is no slab or length is too large to trust, in which case the array
+// should be allocated normally.
+func (r *Reader) allocArray(length Int) []Msg {
+	if r.slab == nil || length > maxPrealloc {
+		return nil
+	}
+	if !r.slab.inUse {
+		r.slab.inUse = true
+		r.last = r.slab
+		r.ringPos = (r.ringPos + 1) % len(r.ring)
+	}
+	return r.slab.alloc(int(length))
+}
+
+// ReleaseLast releases the arrays of the most recently read message back to the ArrayRing so
+// that their storage can be reused by later reads. After calling ReleaseLast, the caller must
+// not use any array, set, or push from that message, including ones nested in other messages.
+// ReleaseLast has no effect if ArrayRing is not set or the last message did not use the ring.
+//
+// Only the most recently read message can be released this way. If another message is read
+// before ReleaseLast is called, the earlier message's buffer stays in use, even if the later
+// message didn't use the ring, until ReleaseAll is called. A pipelined reader that may hold on
+// to a message across reads should call ReleaseAll once it's done with every message it has
+// read, or the ring runs out of free buffers.
+func (r *Reader) ReleaseLast() {
+	if r.last != nil {
+		r.last.release()
+		r.last = nil
+	}
+}
+
+// ReleaseAll releases the arrays of every message read through the ArrayRing, making all of its
+// buffers free for reuse. After calling ReleaseAll, the caller must not use any array, set, or
+// push read since ArrayRing was set, apart from those read after the ring's buffers ran out.
+// ReleaseAll has no effect if ArrayRing is not set.
+func (r *Reader) ReleaseAll() {
+	for _, s := range r.ring {
+		if s.inUse {
+			s.release()
+		}
+	}
+	r.last = nil
+}
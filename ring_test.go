@@ -0,0 +1,149 @@
+package rdx_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_ArrayRing(t *testing.T) {
+	reply := rdx.Array{rdx.String("a"), rdx.Array{rdx.Int(1), rdx.Int(2)}, rdx.Set{rdx.Nil}}
+	var buf bytes.Buffer
+	for i := 0; i < 5; i++ {
+		rdx.Write(&buf, reply)
+	}
+	rdx.Write(&buf, rdx.Int(1))
+
+	r := newRESP3Reader(&buf)
+	r.ArrayRing = 2
+
+	read := func() rdx.Array {
+		t.Helper()
+		msg, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read() err = %v", err)
+		}
+		if !reflect.DeepEqual(msg, reply) {
+			t.Fatalf("Read() = %v; want %v", msg, reply)
+		}
+		return msg.(rdx.Array)
+	}
+	same := func(a, b rdx.Array) bool { return &a[0] == &b[0] }
+
+	first, second := read(), read()
+	if same(first, second) {
+		t.Fatal("consecutive messages share storage")
+	}
+
+	// A released buffer is reused while an unreleased one is skipped.
+	r.ReleaseLast()
+	third := read()
+	if !same(third, second) {
+		t.Fatal("released storage was not reused")
+	}
+
+	// Without a free buffer, arrays are allocated as usual.
+	fourth := read()
+	if same(fourth, first) || same(fourth, third) {
+		t.Fatal("unreleased storage was reused")
+	}
+	r.ReleaseLast() // no-op: the fourth message didn't use the ring
+	if fifth := read(); same(fifth, first) || same(fifth, third) {
+		t.Fatal("unreleased storage was reused")
+	}
+
+	// Non-array messages don't use the ring.
+	if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+		t.Fatalf("Read() = %v, %v; want 1, nil", msg, err)
+	}
+	r.ReleaseLast()
+}
+
+func TestReader_ArrayRing_release(t *testing.T) {
+	msgs := strings.Repeat("*2\r\n:1\r\n*1\r\n:2\r\n", 4)
+	r := rdx.NewReader(strings.NewReader(msgs))
+	r.ArrayRing = 1
+
+	var prev rdx.Array
+	for i := 0; i < 4; i++ {
+		msg, err := r.Read()
+		if err != nil {
+			t.Fatalf("[%d] Read() err = %v", i, err)
+		}
+		ary := msg.(rdx.Array)
+		if want := (rdx.Array{rdx.Int(1), rdx.Array{rdx.Int(2)}}); !reflect.DeepEqual(ary, want) {
+			t.Fatalf("[%d] Read() = %v; want %v", i, ary, want)
+		}
+		if prev != nil && &prev[0] != &ary[0] {
+			t.Fatalf("[%d] released storage was not reused", i)
+		}
+		prev = ary
+		r.ReleaseLast()
+	}
+}
+
+func TestReader_ReleaseAll(t *testing.T) {
+	// An array read before a message that doesn't use the ring can't be released by
+	// ReleaseLast, so its buffer stays in use until ReleaseAll.
+	msgs := strings.Repeat("*1\r\n:1\r\n:2\r\n", 3)
+	r := rdx.NewReader(strings.NewReader(msgs))
+	r.ArrayRing = 1
+
+	readArray := func() rdx.Array {
+		t.Helper()
+		msg, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read() err = %v", err)
+		}
+		return msg.(rdx.Array)
+	}
+	readInt := func() {
+		t.Helper()
+		if msg, err := r.Read(); err != nil || msg != rdx.Int(2) {
+			t.Fatalf("Read() = %v, %v; want 2, nil", msg, err)
+		}
+	}
+
+	first := readArray()
+	readInt()
+	r.ReleaseLast() // no-op: releases only the Int
+	second := readArray()
+	if &first[0] == &second[0] {
+		t.Fatal("storage still in use was reused")
+	}
+	readInt()
+
+	r.ReleaseAll()
+	if third := readArray(); &first[0] != &third[0] {
+		t.Fatal("storage released by ReleaseAll was not reused")
+	}
+	readInt()
+}
+
+func benchmarkArrayRing(b *testing.B, ring int) {
+	var buf bytes.Buffer
+	for i := 0; i < 16; i++ {
+		rdx.Write(&buf, rdx.Array{rdx.Int(1), rdx.Int(2), rdx.Int(3), rdx.Array{rdx.Int(4), rdx.Nil}})
+	}
+	pipeline := buf.Bytes()
+
+	r := rdx.NewReader(bytes.NewReader(pipeline))
+	r.ArrayRing = ring
+	b.ReportAllocs()
+	b.SetBytes(int64(len(pipeline)))
+	for i := 0; i < b.N; i++ {
+		r.Reset(bytes.NewReader(pipeline))
+		for r.More() {
+			if _, err := r.Read(); err != nil {
+				b.Fatal(err)
+			}
+			r.ReleaseLast()
+		}
+	}
+}
+
+func BenchmarkReader_NoArrayRing(b *testing.B) { benchmarkArrayRing(b, 0) }
+func BenchmarkReader_ArrayRing(b *testing.B)   { benchmarkArrayRing(b, 2) }
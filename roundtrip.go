@@ -0,0 +1,36 @@
+package rdx
+
+import (
+	"bytes"
+	"errors"
+)
+
+var ErrTrailingData = errors.New("rdx: message has trailing data")
+
+// RoundTrip encodes m and decodes the result, returning the decoded message. It is intended
+// for tests asserting that a message survives the wire. The decoded message is equal to m, as
+// by Equal, but not always of the same Go type, because the decoder normalizes types:
+//
+//   - SimpleString, BulkString, and Float64 decode as String.
+//   - Null and NilArray decode as Nil.
+//   - A nil Msg decodes as Nil.
+//   - PreEncoded decodes as the message it encodes.
+//
+// If m cannot be encoded, RoundTrip returns the encoding error. If decoding fails or doesn't
+// consume the entire encoding, RoundTrip returns the decoding error or ErrTrailingData.
+func RoundTrip(m Msg) (Msg, error) {
+	b, err := Bytes(m)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewReader(bytes.NewBuffer(b))
+	r.SetProtocol(RESP3)
+	dec, err := r.Read()
+	if err != nil {
+		return nil, err
+	} else if r.More() {
+		return nil, ErrTrailingData
+	}
+	return dec, nil
+}
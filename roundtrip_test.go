@@ -0,0 +1,60 @@
+package rdx_test
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestRoundTrip(t *testing.T) {
+	cached, _ := rdx.Cache(rdx.Int(7))
+	table := []struct {
+		msg  rdx.Msg
+		want rdx.Msg // nil if the message decodes as itself
+	}{
+		{nil, rdx.Nil},
+		{rdx.Nil, nil},
+		{rdx.Null, rdx.Nil},
+		{rdx.NilArray, rdx.Nil},
+		{rdx.Error("ERR x"), nil},
+		{rdx.Int(-42), nil},
+		{rdx.String("a"), nil},
+		{rdx.String{}, nil},
+		{rdx.BulkString("b"), rdx.String("b")},
+		{rdx.SimpleString("c"), rdx.String("c")},
+		{rdx.SimpleString("a\nb"), rdx.String("a\nb")},
+		{rdx.Float64(1.5), rdx.String("1.5")},
+		{rdx.Double(math.Inf(1)), nil},
+		{rdx.BigNumber{Int: big.NewInt(99)}, nil},
+		{rdx.Bool(true), nil},
+		{cached, rdx.Int(7)},
+		{rdx.Array{rdx.Int(1), rdx.BulkString("x")}, rdx.Array{rdx.Int(1), rdx.String("x")}},
+		{rdx.Set{rdx.Int(1)}, nil},
+		{rdx.Push{rdx.String("message")}, nil},
+		{rdx.Map{{Key: rdx.String("k"), Value: rdx.Null}}, rdx.Map{{Key: rdx.String("k"), Value: rdx.Nil}}},
+		{rdx.Attributed{Attrs: rdx.Map{{Key: rdx.String("ttl"), Value: rdx.Int(1)}}, Msg: rdx.Int(2)}, nil},
+	}
+
+	for i, c := range table {
+		want := c.want
+		if want == nil {
+			want = c.msg
+		}
+
+		got, err := rdx.RoundTrip(c.msg)
+		if err != nil {
+			t.Errorf("[%d] RoundTrip(%#v) err = %v", i, c.msg, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("[%d] RoundTrip(%#v) = %#v; want %#v", i, c.msg, got, want)
+		} else if !rdx.Equal(got, c.msg) {
+			t.Errorf("[%d] Equal(RoundTrip(%#v), %#v) = false; want true", i, c.msg, c.msg)
+		}
+	}
+
+	if _, err := rdx.RoundTrip(rdx.Error("\r\n")); err != rdx.ErrInvalidError {
+		t.Errorf("RoundTrip(invalid error) err = %v; want %v", err, rdx.ErrInvalidError)
+	}
+}
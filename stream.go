@@ -0,0 +1,278 @@
+package rdx
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// ReadStream reads the next message. If the message is a bulk string, its payload is not read
+// into memory. Instead, body is a reader limited to the payload, and msg is nil. The bulk
+// string's trailing CRLF is consumed once body has been read in full.
+//
+// The caller must read body until it returns io.EOF (or, equivalently, read exactly as many
+// bytes as the payload contains) before reading another message from r. Until then, all reads
+// from r fail with ErrStreamNotDrained. MaxBulkSize and Arena do not apply to streamed bulk
+// strings.
+//
+// If the message is not a bulk string or is a nil bulk string, body is nil and msg is the
+// message read in full, as by Read. RESP3 streamed strings are also read in full, since their
+// length isn't known up front.
+func (r *Reader) ReadStream() (body io.Reader, msg Msg, err error) {
+	if m, ok := r.takeUnread(); ok {
+		return nil, m, nil
+	}
+
+	head, err := r.readTop()
+	if err != nil {
+		return nil, nil, err
+	} else if head[0] != '$' || r.isStreamed(head) {
+		msg, err = r.readMsg(head)
+		return nil, msg, err
+	}
+
+	length, err := r.bulkLength(head)
+	if err != nil {
+		return nil, nil, err
+	} else if length == -1 {
+		return nil, Nil, nil
+	}
+
+	s := &bulkStream{r: r, n: int64(length)}
+	r.stream = s
+	if length == 0 {
+		// Consume the CRLF up front since there's nothing to read.
+		if err = s.finish(); err != io.EOF {
+			return nil, nil, err
+		}
+	}
+	return s, nil, nil
+}
+
+// SniffLen is the maximum length of the preview passed to the sniff function of
+// ReadBulkSniffed.
+const SniffLen = 512
+
+// ReadBulkSniffed reads the next message, which must be a bulk string. Before the payload is
+// read in full, sniff is called with a preview of up to its first SniffLen bytes. If sniff
+// returns an error, the rest of the payload is discarded and the error is returned, leaving r
+// positioned at the next message. Otherwise, the full payload is returned. The preview is a
+// prefix of the returned string and must not be retained by sniff.
+//
+// If the message is an error, it is returned as the error. If it is a nil bulk string,
+// ReadBulkSniffed returns ErrNilMsg, and if it is any other type of message, it is read in
+// full and a *WrongTypeError is returned. If sniff rejects a payload that is not followed by a
+// CRLF, ErrMissingCRLF is returned instead of sniff's error. RESP3 streamed strings are read in
+// full before sniff is called. MaxBulkSize and MaxMessageSize apply, but Arena is not used.
+func (r *Reader) ReadBulkSniffed(sniff func(preview []byte) error) (String, error) {
+	if m, ok := r.takeUnread(); ok {
+		return sniffMsg(m, sniff)
+	}
+
+	head, err := r.readTop()
+	if err != nil {
+		return nil, err
+	} else if head[0] != '$' {
+		msg, err := r.readMsg(head)
+		if err != nil {
+			return nil, err
+		} else if err := ToError(msg); err != nil {
+			return nil, err
+		}
+		return nil, &WrongTypeError{Got: msg.Type(), Want: TBulkString}
+	} else if r.isStreamed(head) {
+		s, err := r.readStreamedString()
+		if err != nil {
+			return nil, err
+		}
+		return sniffMsg(s, sniff)
+	}
+
+	length, err := r.bulkLength(head)
+	if err != nil {
+		return nil, err
+	} else if length == -1 {
+		return nil, ErrNilMsg
+	} else if r.MaxBulkSize > 0 && length > Int(r.MaxBulkSize) {
+		return nil, ErrBulkTooLarge
+	} else if err := r.checkSize(int64(length) + 2); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, length+2)
+	preview := buf[:length]
+	if len(preview) > SniffLen {
+		preview = preview[:SniffLen]
+	}
+	if err := r.readFull(preview); err != nil {
+		return nil, err
+	}
+
+	if err := sniff(preview[:len(preview):len(preview)]); err != nil {
+		// Discard the rest of the payload so that the next message can be read.
+		n, derr := io.CopyN(ioutil.Discard, r.src.r, int64(length)-int64(len(preview)))
+		*r.nread += n
+		if derr == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else if derr != nil {
+			return nil, derr
+		} else if derr = r.readFull(buf[length:]); derr != nil {
+			return nil, derr
+		} else if !bytes.Equal(buf[length:], crlf) {
+			return nil, ErrMissingCRLF
+		}
+		return nil, err
+	}
+
+	if err := r.readFull(buf[len(preview):]); err != nil {
+		return nil, err
+	} else if !bytes.HasSuffix(buf, crlf) {
+		return nil, ErrMissingCRLF
+	} else if length == 0 {
+		return String{}, nil
+	}
+	return String(buf[:length:length]), nil
+}
+
+// ReadBulkAppend reads the next message, which must be a bulk string, and appends its payload
+// to dst, returning the extended slice and TBulkString. The payload is read directly into dst
+// when it has enough capacity, so reusing dst across calls avoids allocating for each message.
+//
+// For any other message, dst is returned unchanged along with the message's type: if it is an
+// error, it is returned as the error; if it is a nil bulk string, ErrNilMsg is returned; and
+// otherwise a *WrongTypeError is returned. RESP3 streamed strings are appended in full.
+// MaxBulkSize and MaxMessageSize apply, but Arena is not used.
+func (r *Reader) ReadBulkAppend(dst []byte) ([]byte, Type, error) {
+	if m, ok := r.takeUnread(); ok {
+		return appendMsg(dst, m)
+	}
+
+	head, err := r.readTop()
+	if err != nil {
+		return dst, 0, err
+	} else if head[0] != '$' {
+		msg, err := r.readMsg(head)
+		if err != nil {
+			return dst, 0, err
+		}
+		return appendMsg(dst, msg)
+	} else if r.isStreamed(head) {
+		s, err := r.readStreamedString()
+		if err != nil {
+			return dst, 0, err
+		}
+		return append(dst, s...), TBulkString, nil
+	}
+
+	length, err := r.bulkLength(head)
+	if err != nil {
+		return dst, 0, err
+	} else if length == -1 {
+		return dst, TNil, ErrNilMsg
+	} else if r.MaxBulkSize > 0 && length > Int(r.MaxBulkSize) {
+		return dst, 0, ErrBulkTooLarge
+	} else if err := r.checkSize(int64(length) + 2); err != nil {
+		return dst, 0, err
+	}
+
+	n := len(dst)
+	if cap(dst)-n < int(length) {
+		grown := make([]byte, n, n+int(length))
+		copy(grown, dst)
+		dst = grown
+	}
+	if _, err := r.readStringInto(dst[n : n+int(length)]); err != nil {
+		return dst[:n], 0, err
+	}
+	return dst[:n+int(length)], TBulkString, nil
+}
+
+// appendMsg returns m as ReadBulkAppend would if it had read m.
+func appendMsg(dst []byte, m Msg) ([]byte, Type, error) {
+	typ := m.Type()
+	if err := ToError(m); err != nil {
+		return dst, typ, err
+	} else if typ == TNil {
+		return dst, typ, ErrNilMsg
+	} else if typ != TBulkString {
+		return dst, typ, &WrongTypeError{Got: typ, Want: TBulkString}
+	}
+	return append(dst, m.String()...), typ, nil
+}
+
+// sniffMsg returns m as ReadBulkSniffed would if it had read m in full, calling sniff with a
+// preview of m if it is a string.
+func sniffMsg(m Msg, sniff func(preview []byte) error) (String, error) {
+	if err := ToError(m); err != nil {
+		return nil, err
+	} else if IsA(m, TNil) {
+		return nil, ErrNilMsg
+	} else if !IsA(m, TBulkString) {
+		return nil, &WrongTypeError{Got: m.Type(), Want: TBulkString}
+	}
+
+	s, ok := m.(String)
+	if !ok {
+		s = String(m.String())
+	}
+	preview := s
+	if len(preview) > SniffLen {
+		preview = preview[:SniffLen]
+	}
+	if err := sniff(preview[:len(preview):len(preview)]); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// bulkStream reads the payload of a bulk string from a Reader.
+type bulkStream struct {
+	r   *Reader
+	n   int64 // bytes of payload remaining
+	err error // sticky error, set once the payload and CRLF have been read or reading failed
+}
+
+func (s *bulkStream) Read(p []byte) (n int, err error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	if int64(len(p)) > s.n {
+		p = p[:s.n]
+	}
+	n, err = s.r.src.r.Read(p)
+	s.n -= int64(n)
+	*s.r.nread += int64(n)
+
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		s.err = err
+		s.r.stream = nil
+		return n, err
+	}
+
+	if s.n == 0 {
+		err = s.finish()
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	return n, err
+}
+
+// finish consumes the trailing CRLF of the bulk string and releases the Reader.
+func (s *bulkStream) finish() error {
+	s.r.stream = nil
+
+	var tail [2]byte
+	if err := s.r.readFull(tail[:]); err != nil {
+		s.err = err
+	} else if tail[0] != '\r' || tail[1] != '\n' {
+		s.err = ErrMissingCRLF
+	} else {
+		s.err = io.EOF
+	}
+	return s.err
+}
@@ -0,0 +1,255 @@
+package rdx_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_ReadStream(t *testing.T) {
+	payload := strings.Repeat("0123456789", 1000)
+	stream := "$" + "10000\r\n" + payload + "\r\n" +
+		"$0\r\n\r\n" +
+		"$-1\r\n" +
+		"$?\r\n;2\r\nab\r\n;0\r\n" +
+		":1\r\n"
+
+	r := newRESP3Reader(iotest.HalfReader(strings.NewReader(stream)))
+
+	body, msg, err := r.ReadStream()
+	if err != nil || msg != nil || body == nil {
+		t.Fatalf("ReadStream() = %v, %v, %v; want body, nil, nil", body, msg, err)
+	}
+
+	// The stream must be drained before the next message can be read.
+	if _, err := r.Read(); err != rdx.ErrStreamNotDrained {
+		t.Fatalf("Read() err = %v; want %v", err, rdx.ErrStreamNotDrained)
+	}
+
+	var dst bytes.Buffer
+	if n, err := io.Copy(&dst, body); err != nil || n != int64(len(payload)) {
+		t.Fatalf("Copy() = %d, %v; want %d, nil", n, err, len(payload))
+	} else if dst.String() != payload {
+		t.Fatal("streamed payload does not match")
+	}
+
+	// Empty bulk strings are drained immediately.
+	body, msg, err = r.ReadStream()
+	if err != nil || msg != nil {
+		t.Fatalf("ReadStream() = %v, %v, %v; want body, nil, nil", body, msg, err)
+	} else if b, err := ioutil.ReadAll(body); err != nil || len(b) != 0 {
+		t.Fatalf("ReadAll() = %q, %v; want empty, nil", b, err)
+	}
+
+	// Non-bulk messages and nil bulk strings are read in full.
+	if body, msg, err = r.ReadStream(); err != nil || body != nil || msg != rdx.Nil {
+		t.Fatalf("ReadStream() = %v, %v, %v; want nil, Nil, nil", body, msg, err)
+	}
+	if body, msg, err = r.ReadStream(); err != nil || body != nil || !rdx.Equal(msg, rdx.String("ab")) {
+		t.Fatalf("ReadStream() = %v, %v, %v; want nil, ab, nil", body, msg, err)
+	}
+	if body, msg, err = r.ReadStream(); err != nil || body != nil || msg != rdx.Int(1) {
+		t.Fatalf("ReadStream() = %v, %v, %v; want nil, 1, nil", body, msg, err)
+	}
+}
+
+func TestReader_ReadStreamExact(t *testing.T) {
+	// Reading exactly the payload length drains the stream without a trailing read.
+	r := rdx.NewReader(strings.NewReader("$5\r\nhello\r\n:1\r\n"))
+	body, _, err := r.ReadStream()
+	if err != nil {
+		t.Fatalf("ReadStream() err = %v", err)
+	}
+
+	var dst bytes.Buffer
+	if _, err := io.CopyN(&dst, body, 5); err != nil {
+		t.Fatalf("CopyN() err = %v", err)
+	}
+	if msg, err := r.Read(); err != nil || msg != rdx.Int(1) {
+		t.Fatalf("Read() = %v, %v; want 1, nil", msg, err)
+	}
+}
+
+func TestReader_ReadStreamErrors(t *testing.T) {
+	table := []struct {
+		msg string
+		err error
+	}{
+		{"$5\r\nhel", io.ErrUnexpectedEOF},
+		{"$5\r\nhelloxx", rdx.ErrMissingCRLF},
+		{"$5\r\nhello", io.ErrUnexpectedEOF},
+	}
+
+	for i, c := range table {
+		body, _, err := rdx.NewReader(strings.NewReader(c.msg)).ReadStream()
+		if err != nil {
+			t.Errorf("[%d] ReadStream() err = %v", i, err)
+			continue
+		}
+		if _, err := ioutil.ReadAll(body); err != c.err {
+			t.Errorf("[%d] ReadAll() err = %v; want %v", i, err, c.err)
+		}
+	}
+
+	if _, _, err := rdx.NewReader(strings.NewReader("$-2\r\n")).ReadStream(); err != rdx.ErrNegativeLength {
+		t.Errorf("ReadStream() err = %v; want %v", err, rdx.ErrNegativeLength)
+	}
+}
+
+func TestReader_ReadBulkSniffed(t *testing.T) {
+	errBinary := errors.New("binary payload")
+	rejectBinary := func(preview []byte) error {
+		if bytes.IndexByte(preview, 0) >= 0 {
+			return errBinary
+		}
+		return nil
+	}
+
+	large := `{"k":"` + strings.Repeat("v", rdx.SniffLen*2) + `"}`
+	stream := "$" + strconv.Itoa(len(large)) + "\r\n" + large + "\r\n" +
+		"$4\r\nab\x00c\r\n" +
+		"$0\r\n\r\n" +
+		":1\r\n" +
+		"-ERR x\r\n" +
+		"$-1\r\n" +
+		"$?\r\n;2\r\nab\r\n;1\r\nc\r\n;0\r\n" +
+		"$?\r\n;2\r\nab\r\n;1\r\n\x00\r\n;0\r\n" +
+		"$3\r\nab"
+
+	r := newRESP3Reader(iotest.OneByteReader(strings.NewReader(stream)))
+
+	var previews []string
+	sniff := func(preview []byte) error {
+		previews = append(previews, string(preview))
+		return rejectBinary(preview)
+	}
+
+	// The full payload is read after a passing sniff, which only sees a prefix.
+	if s, err := r.ReadBulkSniffed(sniff); err != nil || string(s) != large {
+		t.Fatalf("ReadBulkSniffed() = %.20q, %v; want %.20q, nil", s, err, large)
+	} else if len(previews) != 1 || previews[0] != large[:rdx.SniffLen] {
+		t.Fatalf("previews = %.20q; want %.20q", previews, large[:rdx.SniffLen])
+	}
+
+	// A rejected payload is discarded.
+	if s, err := r.ReadBulkSniffed(sniff); err != errBinary || s != nil {
+		t.Fatalf("ReadBulkSniffed() = %q, %v; want nil, %v", s, err, errBinary)
+	}
+
+	if s, err := r.ReadBulkSniffed(sniff); err != nil || len(s) != 0 {
+		t.Fatalf("ReadBulkSniffed() = %q, %v; want empty, nil", s, err)
+	} else if len(previews) != 3 || previews[2] != "" {
+		t.Fatalf("previews = %.20q; want empty last preview", previews)
+	}
+
+	for _, want := range []error{rdx.ErrWrongType, rdx.Error("ERR x"), rdx.ErrNilMsg} {
+		if s, err := r.ReadBulkSniffed(sniff); !errors.Is(err, want) || s != nil {
+			t.Fatalf("ReadBulkSniffed() = %q, %v; want nil, %v", s, err, want)
+		}
+	}
+
+	// Streamed strings are sniffed once read in full.
+	if s, err := r.ReadBulkSniffed(sniff); err != nil || string(s) != "abc" {
+		t.Fatalf("ReadBulkSniffed() = %q, %v; want abc, nil", s, err)
+	} else if last := previews[len(previews)-1]; last != "abc" {
+		t.Fatalf("last preview = %q; want abc", last)
+	}
+	if s, err := r.ReadBulkSniffed(sniff); err != errBinary || s != nil {
+		t.Fatalf("ReadBulkSniffed() = %q, %v; want nil, %v", s, err, errBinary)
+	}
+
+	if s, err := r.ReadBulkSniffed(sniff); err != io.ErrUnexpectedEOF || s != nil {
+		t.Fatalf("ReadBulkSniffed() = %q, %v; want nil, %v", s, err, io.ErrUnexpectedEOF)
+	}
+
+	var wrongType *rdx.WrongTypeError
+	r = rdx.NewReader(strings.NewReader("*0\r\n"))
+	if _, err := r.ReadBulkSniffed(sniff); !errors.As(err, &wrongType) || err.Error() != "rdx: got array message; want bulk string" {
+		t.Fatalf("ReadBulkSniffed() err = %v; want *WrongTypeError", err)
+	}
+
+	// A rejected payload must still be followed by a CRLF.
+	r = rdx.NewReader(strings.NewReader("$4\r\nab\x00cXY"))
+	if s, err := r.ReadBulkSniffed(sniff); err != rdx.ErrMissingCRLF || s != nil {
+		t.Fatalf("ReadBulkSniffed() = %q, %v; want nil, %v", s, err, rdx.ErrMissingCRLF)
+	}
+}
+
+func TestReader_ReadBulkAppend(t *testing.T) {
+	stream := "$3\r\nabc\r\n" +
+		"$0\r\n\r\n" +
+		"$?\r\n;2\r\nde\r\n;1\r\nf\r\n;0\r\n" +
+		":1\r\n" +
+		"-ERR x\r\n" +
+		"$-1\r\n" +
+		"$2\r\ngh\r\n" +
+		"$2\r\nijXY"
+	r := newRESP3Reader(iotest.OneByteReader(strings.NewReader(stream)))
+
+	dst := make([]byte, 0, 4)
+	for i, c := range []struct {
+		want string
+		typ  rdx.Type
+		err  error
+	}{
+		{"abc", rdx.TBulkString, nil},
+		{"abc", rdx.TBulkString, nil},
+		{"abcdef", rdx.TBulkString, nil},
+		{"abcdef", rdx.TInt, rdx.ErrWrongType},
+		{"abcdef", rdx.TError, rdx.Error("ERR x")},
+		{"abcdef", rdx.TNil, rdx.ErrNilMsg},
+		{"abcdefgh", rdx.TBulkString, nil},
+		{"abcdefgh", 0, rdx.ErrMissingCRLF},
+	} {
+		got, typ, err := r.ReadBulkAppend(dst)
+		if string(got) != c.want || typ != c.typ || !errors.Is(err, c.err) {
+			t.Fatalf("[%d] ReadBulkAppend() = %q, %v, %v; want %q, %v, %v", i, got, typ, err, c.want, c.typ, c.err)
+		}
+		dst = got
+	}
+
+	// The payload is read into dst when it has room.
+	dst = make([]byte, 1, 8)
+	r = rdx.NewReader(strings.NewReader("$3\r\nabc\r\n"))
+	if got, _, err := r.ReadBulkAppend(dst); err != nil || string(got) != "\x00abc" || &got[0] != &dst[0] {
+		t.Fatalf("ReadBulkAppend() = %q, %v; want %q in dst, nil", got, err, "\x00abc")
+	}
+
+	var wrongType *rdx.WrongTypeError
+	r = rdx.NewReader(strings.NewReader("*0\r\n"))
+	if _, _, err := r.ReadBulkAppend(nil); !errors.As(err, &wrongType) || err.Error() != "rdx: got array message; want bulk string" {
+		t.Fatalf("ReadBulkAppend() err = %v; want *WrongTypeError", err)
+	}
+}
+
+func TestReader_HugeBulkLength(t *testing.T) {
+	const stream = "$9223372036854775806\r\n"
+
+	r := rdx.NewReader(strings.NewReader(stream))
+	if _, err := r.ReadBulkSniffed(func([]byte) error { return nil }); err != rdx.ErrBulkTooLarge {
+		t.Errorf("ReadBulkSniffed() err = %v; want %v", err, rdx.ErrBulkTooLarge)
+	}
+
+	r = rdx.NewReader(strings.NewReader(stream))
+	if _, _, err := r.ReadBulkAppend(nil); err != rdx.ErrBulkTooLarge {
+		t.Errorf("ReadBulkAppend() err = %v; want %v", err, rdx.ErrBulkTooLarge)
+	}
+
+	r = rdx.NewReader(strings.NewReader(stream))
+	if _, _, err := r.ReadStream(); err != rdx.ErrBulkTooLarge {
+		t.Errorf("ReadStream() err = %v; want %v", err, rdx.ErrBulkTooLarge)
+	}
+
+	r = rdx.NewReader(strings.NewReader(stream))
+	r.Arena = rdx.NewArena(16)
+	if _, err := r.Read(); err != rdx.ErrBulkTooLarge {
+		t.Errorf("Read() with Arena err = %v; want %v", err, rdx.ErrBulkTooLarge)
+	}
+}
@@ -0,0 +1,39 @@
+package rdx
+
+import "time"
+
+// Time returns t as an Int of seconds since the Unix epoch, as used by commands such as
+// EXPIREAT and returned by EXPIRETIME. Fractions of a second are truncated.
+func Time(t time.Time) Msg {
+	return Int(t.Unix())
+}
+
+// Duration returns d as an Int of milliseconds, as used by commands such as PEXPIRE and
+// returned by PTTL. Fractions of a millisecond are truncated.
+func Duration(d time.Duration) Msg {
+	return Int(d.Milliseconds())
+}
+
+// ToTime converts msg, in seconds since the Unix epoch, to a time.Time. It accepts the same
+// messages as ToInt and returns the same errors.
+func ToTime(msg Msg) (time.Time, error) {
+	n, err := ToInt(msg)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(n, 0), nil
+}
+
+// ToDuration converts msg, in milliseconds, to a time.Duration. It accepts the same messages as
+// ToInt and returns the same errors, or ErrIntRange if the duration would overflow.
+func ToDuration(msg Msg) (time.Duration, error) {
+	n, err := ToInt(msg)
+	if err != nil {
+		return 0, err
+	}
+	const max = int64(1<<63-1) / int64(time.Millisecond)
+	if n > max || n < -max {
+		return 0, ErrIntRange
+	}
+	return time.Duration(n) * time.Millisecond, nil
+}
@@ -0,0 +1,48 @@
+package rdx_test
+
+import (
+	"testing"
+	"time"
+
+	"go.spiff.io/rdx"
+)
+
+func TestTime(t *testing.T) {
+	ts := time.Date(2021, 11, 28, 18, 45, 33, 500, time.UTC)
+	m := rdx.Time(ts)
+	if m != rdx.Int(1638125133) {
+		t.Fatalf("Time() = %#v; want rdx.Int(1638125133)", m)
+	}
+
+	got, err := rdx.ToTime(rdx.BulkString("1638125133"))
+	if err != nil || !got.Equal(ts.Truncate(time.Second)) {
+		t.Fatalf("ToTime() = %v, %v; want %v, nil", got, err, ts.Truncate(time.Second))
+	}
+	if _, err := rdx.ToTime(rdx.Nil); err != rdx.ErrNilMsg {
+		t.Fatalf("ToTime(Nil) err = %v; want %v", err, rdx.ErrNilMsg)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	d := 90*time.Second + 500*time.Microsecond
+	if m := rdx.Duration(d); m != rdx.Int(90000) {
+		t.Fatalf("Duration() = %#v; want rdx.Int(90000)", m)
+	}
+
+	for i, c := range []struct {
+		msg  rdx.Msg
+		want time.Duration
+		err  error
+	}{
+		{rdx.Int(90000), 90 * time.Second, nil},
+		{rdx.Int(-2), -2 * time.Millisecond, nil},
+		{rdx.String("1500"), 1500 * time.Millisecond, nil},
+		{rdx.Int(1 << 62), 0, rdx.ErrIntRange},
+		{rdx.Array(nil), 0, rdx.ErrWrongType},
+	} {
+		got, err := rdx.ToDuration(c.msg)
+		if got != c.want || err != c.err {
+			t.Errorf("[%d] ToDuration(%v) = %v, %v; want %v, %v", i, c.msg, got, err, c.want, c.err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package rdx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// InvalidUnmarshalError is returned by Unmarshal when it is passed something other than a
+// non-nil pointer.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "rdx: Unmarshal(nil)"
+	} else if e.Type.Kind() != reflect.Ptr {
+		return "rdx: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "rdx: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// UnmarshalTypeError is returned by Unmarshal when a Msg cannot be stored in a Go value of the
+// given type. If the value is a struct field, Field is the name of the field.
+type UnmarshalTypeError struct {
+	Msg   Msg
+	Type  reflect.Type
+	Field string
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	s := fmt.Sprintf("rdx: cannot unmarshal %T into Go value of type %v", e.Msg, e.Type)
+	if e.Field != "" {
+		s += " (field " + e.Field + ")"
+	}
+	return s
+}
+
+var ifaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// Unmarshal stores the value of m in the Go value pointed to by v. It is the inverse of
+// Marshal, with the following conversions:
+//
+//   - Nil (or a nil Msg) sets the target to its zero value, so pointers, slices, and maps are
+//     set to nil.
+//   - Targets of type Msg or interface{}, or of the Msg's own type, are set to the Msg as-is.
+//   - An Error is returned as the error of Unmarshal rather than stored in other targets.
+//   - Pointers are allocated as needed and the Msg is stored in the value they point to.
+//   - Strings and byte slices accept any string, integer, double, big number, or boolean.
+//   - Integers accept what ToInt accepts: an Int, a BigNumber, or a string holding a base-10
+//     integer. Values that overflow the target return ErrIntRange.
+//   - Floats accept what ToFloat accepts: any integer, double, big number, or string holding
+//     a number.
+//   - Booleans accept what ToBool accepts: a Bool, an Int of 0 or 1, or a string holding "1",
+//     "0", "true", "false", or "OK".
+//   - Slices and arrays accept an Array, Set, or Push. Arrays are filled up to their length
+//     and any remaining elements are zeroed.
+//   - Maps and structs accept a Map, or an Array of alternating keys and values such as the
+//     RESP2 reply of CONFIG GET or HGETALL. Struct fields are matched by the name given in
+//     an `rdx:"name"` tag, or else by the field name, ignoring case. Fields tagged `rdx:"-"`
+//     and keys without a matching field are ignored.
+//
+// Any other conversion returns an *UnmarshalTypeError. If v is not a non-nil pointer,
+// Unmarshal returns an *InvalidUnmarshalError.
+func Unmarshal(m Msg, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	return unmarshalValue(ensure(m), rv.Elem(), "")
+}
+
+func unmarshalValue(m Msg, v reflect.Value, field string) error {
+	t := v.Type()
+	if t == msgType || t == ifaceType || reflect.TypeOf(m) == t {
+		v.Set(reflect.ValueOf(m))
+		return nil
+	}
+
+	m = ensure(StripAttrs(m))
+	if e, ok := m.(Error); ok {
+		return e
+	} else if m.Type() == TNil {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+
+	mismatch := &UnmarshalTypeError{Msg: m, Type: t, Field: field}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return unmarshalValue(m, v.Elem(), field)
+
+	case reflect.String:
+		if !isScalar(m) {
+			return mismatch
+		}
+		v.SetString(m.String())
+
+	case reflect.Bool:
+		b, err := ToBool(m)
+		if err != nil {
+			return mismatch
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := ToInt(m)
+		if err == ErrIntRange {
+			return err
+		} else if err != nil {
+			return mismatch
+		} else if v.OverflowInt(n) {
+			return ErrIntRange
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := ToInt(m)
+		if err == ErrIntRange {
+			return err
+		} else if err != nil {
+			return mismatch
+		} else if n < 0 || v.OverflowUint(uint64(n)) {
+			return ErrIntRange
+		}
+		v.SetUint(uint64(n))
+
+	case reflect.Float32, reflect.Float64:
+		if !isScalar(m) {
+			return mismatch
+		}
+		f, err := ToFloat(m)
+		if err != nil {
+			return mismatch
+		}
+		v.SetFloat(f)
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && isScalar(m) {
+			v.SetBytes([]byte(m.String()))
+			return nil
+		}
+		elems, ok := msgElems(m)
+		if !ok {
+			return mismatch
+		}
+		s := reflect.MakeSlice(t, len(elems), len(elems))
+		for i, elem := range elems {
+			if err := unmarshalValue(ensure(elem), s.Index(i), field); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+
+	case reflect.Array:
+		elems, ok := msgElems(m)
+		if !ok {
+			return mismatch
+		}
+		for i := 0; i < v.Len(); i++ {
+			if i >= len(elems) {
+				v.Index(i).Set(reflect.Zero(t.Elem()))
+			} else if err := unmarshalValue(ensure(elems[i]), v.Index(i), field); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		pairs, ok, err := unmarshalPairs(m)
+		if err != nil {
+			return err
+		} else if !ok {
+			return mismatch
+		}
+		mv := reflect.MakeMapWithSize(t, len(pairs))
+		for _, p := range pairs {
+			key := reflect.New(t.Key()).Elem()
+			if err := unmarshalValue(ensure(p.Key), key, field); err != nil {
+				return err
+			}
+			val := reflect.New(t.Elem()).Elem()
+			if err := unmarshalValue(ensure(p.Value), val, field); err != nil {
+				return err
+			}
+			mv.SetMapIndex(key, val)
+		}
+		v.Set(mv)
+
+	case reflect.Struct:
+		pairs, ok, err := unmarshalPairs(m)
+		if err != nil {
+			return err
+		} else if !ok {
+			return mismatch
+		}
+		for _, p := range pairs {
+			key := ensure(p.Key)
+			if !isScalar(key) {
+				return &UnmarshalTypeError{Msg: key, Type: t, Field: field}
+			}
+			i, ok := structField(t, key.String())
+			if !ok {
+				continue
+			}
+			f := t.Field(i)
+			if err := unmarshalValue(ensure(p.Value), v.Field(i), f.Name); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+// isScalar reports whether m has a string form that can be stored in a string.
+func isScalar(m Msg) bool {
+	switch m.(type) {
+	case String, BulkString, SimpleString, Int, Float64, Double, BigNumber, Bool:
+		return true
+	}
+	return false
+}
+
+// unmarshalPairs returns the pairs of a Map or of an Array of alternating keys and values.
+func unmarshalPairs(m Msg) (pairs []Pair, ok bool, err error) {
+	switch m := m.(type) {
+	case Map:
+		return m, true, nil
+	case Array:
+		pairs, err = m.OrderedPairs()
+		return pairs, true, err
+	}
+	return nil, false, nil
+}
+
+// structField returns the index of the exported field of t named by key, either through its
+// rdx tag or, failing that, by its name ignoring case.
+func structField(t reflect.Type, key string) (int, bool) {
+	byName := -1
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("rdx")
+		if tag == "-" {
+			continue
+		} else if tag != "" {
+			if tag == key {
+				return i, true
+			}
+			continue
+		}
+		if byName < 0 && strings.EqualFold(f.Name, key) {
+			byName = i
+		}
+	}
+	return byName, byName >= 0
+}
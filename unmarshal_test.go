@@ -0,0 +1,155 @@
+package rdx_test
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+type configReply struct {
+	MaxMemory  int64  `rdx:"maxmemory"`
+	Policy     string `rdx:"maxmemory-policy"`
+	AppendOnly string `rdx:"appendonly"`
+	Timeout    *int
+	Ignored    string `rdx:"-"`
+	unexported string
+}
+
+func TestUnmarshal(t *testing.T) {
+	var (
+		five  = 5
+		bulk  = func(s string) rdx.Msg { return rdx.String(s) }
+		array = rdx.Array{rdx.Int(1), rdx.Int(2)}
+	)
+
+	table := []struct {
+		in   rdx.Msg
+		into interface{} // pointer to the initial value
+		want interface{}
+	}{
+		{rdx.Int(1), new(int), 1},
+		{rdx.Int(-1), new(int8), int8(-1)},
+		{bulk("42"), new(uint16), uint16(42)},
+		{rdx.Int(7), new(float64), 7.0},
+		{rdx.Double(1.5), new(float32), float32(1.5)},
+		{bulk("2.5"), new(float64), 2.5},
+		{bulk("foo"), new(string), "foo"},
+		{rdx.SimpleString("OK"), new(string), "OK"},
+		{rdx.Int(3), new(string), "3"},
+		{bulk("bar"), new([]byte), []byte("bar")},
+		{rdx.Bool(true), new(bool), true},
+		{rdx.Int(0), new(bool), false},
+		{rdx.SimpleString("OK"), new(bool), true},
+		{bulk("false"), new(bool), false},
+		{rdx.BigNumber{Int: big.NewInt(-9)}, new(int64), int64(-9)},
+		{rdx.BigNumber{Int: big.NewInt(9)}, new(uint), uint(9)},
+		{rdx.BigNumber{Int: big.NewInt(3)}, new(float64), 3.0},
+		{rdx.Int(5), new(*int), &five},
+		{rdx.Int(5), new(rdx.Msg), rdx.Msg(rdx.Int(5))},
+		{rdx.Int(5), new(interface{}), interface{}(rdx.Int(5))},
+		{array, new(rdx.Array), array},
+
+		// Nil zeroes the target.
+		{rdx.Nil, &[]int{1}[0], 0},
+		{nil, func() interface{} { p := new(int); return &p }(), (*int)(nil)},
+		{rdx.Nil, &[]string{"a"}, []string(nil)},
+		{rdx.Nil, &map[string]int{"a": 1}, map[string]int(nil)},
+
+		{array, new([]int), []int{1, 2}},
+		{rdx.Set{bulk("a")}, new([]string), []string{"a"}},
+		{array, new([3]int), [3]int{1, 2, 0}},
+		{array, &[1]int{9}, [1]int{1}},
+		{rdx.Array{rdx.Array{rdx.Int(1)}, rdx.Nil}, new([][]int), [][]int{{1}, nil}},
+
+		{rdx.Map{{Key: bulk("a"), Value: rdx.Int(1)}}, new(map[string]int), map[string]int{"a": 1}},
+		{rdx.Array{bulk("a"), bulk("1"), bulk("b"), bulk("2")}, new(map[string]int), map[string]int{"a": 1, "b": 2}},
+
+		// CONFIG GET in RESP2 and RESP3.
+		{
+			rdx.Array{
+				bulk("maxmemory"), bulk("1024"),
+				bulk("maxmemory-policy"), bulk("noeviction"),
+				bulk("appendonly"), bulk("no"),
+				bulk("timeout"), bulk("5"),
+				bulk("ignored"), bulk("x"),
+				bulk("unexported"), bulk("x"),
+				bulk("unknown"), bulk("x"),
+			},
+			new(configReply),
+			configReply{MaxMemory: 1024, Policy: "noeviction", AppendOnly: "no", Timeout: &five},
+		},
+		{
+			rdx.Map{
+				{Key: bulk("maxmemory"), Value: rdx.Int(1)},
+				{Key: bulk("appendonly"), Value: rdx.Bool(true)},
+				{Key: bulk("Ignored"), Value: bulk("x")},
+			},
+			&configReply{Policy: "kept"},
+			configReply{MaxMemory: 1, Policy: "kept", AppendOnly: "true"},
+		},
+	}
+
+	for i, c := range table {
+		if err := rdx.Unmarshal(c.in, c.into); err != nil {
+			t.Errorf("[%d] Unmarshal(%v) err = %v", i, c.in, err)
+			continue
+		}
+		if got := reflect.ValueOf(c.into).Elem().Interface(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%d] Unmarshal(%v) = %#v; want %#v", i, c.in, got, c.want)
+		}
+	}
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	var (
+		n   int
+		s   string
+		cfg configReply
+		ute *rdx.UnmarshalTypeError
+		iue *rdx.InvalidUnmarshalError
+	)
+
+	if err := rdx.Unmarshal(rdx.Int(1), n); !errors.As(err, &iue) {
+		t.Errorf("Unmarshal(non-pointer) err = %v; want *InvalidUnmarshalError", err)
+	}
+	if err := rdx.Unmarshal(rdx.Int(1), (*int)(nil)); !errors.As(err, &iue) {
+		t.Errorf("Unmarshal(nil pointer) err = %v; want *InvalidUnmarshalError", err)
+	}
+
+	if err := rdx.Unmarshal(rdx.String("x"), &n); !errors.As(err, &ute) || ute.Type != reflect.TypeOf(n) {
+		t.Errorf("Unmarshal(x, *int) err = %v; want *UnmarshalTypeError", err)
+	}
+	if err := rdx.Unmarshal(rdx.Array(nil), &s); !errors.As(err, &ute) {
+		t.Errorf("Unmarshal([], *string) err = %v; want *UnmarshalTypeError", err)
+	}
+	err := rdx.Unmarshal(rdx.Array{rdx.String("maxmemory"), rdx.String("lots")}, &cfg)
+	if !errors.As(err, &ute) || ute.Field != "MaxMemory" {
+		t.Errorf("Unmarshal(config) err = %v; want *UnmarshalTypeError for MaxMemory", err)
+	}
+
+	if err := rdx.Unmarshal(rdx.Int(300), new(int8)); err != rdx.ErrIntRange {
+		t.Errorf("Unmarshal(300, *int8) err = %v; want %v", err, rdx.ErrIntRange)
+	}
+	// Unmarshal accepts the same strings as ToBool.
+	if err := rdx.Unmarshal(rdx.String("yes"), new(bool)); !errors.As(err, &ute) {
+		t.Errorf("Unmarshal(yes, *bool) err = %v; want *UnmarshalTypeError", err)
+	}
+	huge, _ := new(big.Int).SetString("99999999999999999999", 10)
+	if err := rdx.Unmarshal(rdx.BigNumber{Int: huge}, new(int64)); err != rdx.ErrIntRange {
+		t.Errorf("Unmarshal(%v, *int64) err = %v; want %v", huge, err, rdx.ErrIntRange)
+	}
+	if err := rdx.Unmarshal(rdx.Int(-1), new(uint)); err != rdx.ErrIntRange {
+		t.Errorf("Unmarshal(-1, *uint) err = %v; want %v", err, rdx.ErrIntRange)
+	}
+	if err := rdx.Unmarshal(rdx.Array{rdx.String("a")}, new(map[string]string)); err != rdx.ErrOddMapLength {
+		t.Errorf("Unmarshal(odd array, *map) err = %v; want %v", err, rdx.ErrOddMapLength)
+	}
+
+	e := rdx.Error("ERR no such key")
+	if err := rdx.Unmarshal(e, &s); err != e {
+		t.Errorf("Unmarshal(error, *string) err = %v; want %v", err, e)
+	}
+}
@@ -0,0 +1,62 @@
+package rdx
+
+import (
+	"bytes"
+	"errors"
+)
+
+var ErrInvalidUnreadMsg = errors.New("rdx: invalid use of UnreadMsg")
+
+// UnreadMsg pushes m back onto the Reader so that it is returned by the next read, such as
+// Read or ReadCommandName, without touching the underlying reader. A nil m is unread as Nil.
+// Only one message can be pushed back at a time: calling UnreadMsg again before m has been
+// read returns ErrInvalidUnreadMsg, as with bufio.Reader's UnreadByte.
+//
+// The message is returned as-is, so limits such as MaxBulkSize don't apply to it and it isn't
+// counted by BytesRead again. PeekType reports the message's own Type. If the Reader was
+// returned by ReadCommandName, the unread message counts as one of the remaining arguments.
+func (r *Reader) UnreadMsg(m Msg) error {
+	if r.unread != nil {
+		return ErrInvalidUnreadMsg
+	}
+	r.unread = ensure(m)
+	if r.cmd {
+		r.args++
+	}
+	return nil
+}
+
+// takeUnread returns the message pushed back by UnreadMsg, if any, and clears it.
+func (r *Reader) takeUnread() (Msg, bool) {
+	m := r.unread
+	if m == nil {
+		return nil, false
+	}
+	r.unread = nil
+	if r.cmd {
+		r.args--
+	}
+	return m, true
+}
+
+// commandFromMsg returns the name and arguments of an unread command in the form returned by
+// ReadCommandName.
+func (r *Reader) commandFromMsg(m Msg) (name string, rest *Reader, err error) {
+	args, ok := m.(Array)
+	if !ok || len(args) == 0 {
+		return "", nil, ErrNotCommand
+	}
+
+	var buf bytes.Buffer
+	for _, arg := range args[1:] {
+		if err := writeElem(&buf, arg); err != nil {
+			return "", nil, err
+		}
+	}
+	rest = r.argsReader(&buf, len(args)-1)
+
+	if !IsA(args[0], TString) {
+		return "", rest, ErrNotCommand
+	}
+	return args[0].String(), rest, nil
+}
@@ -0,0 +1,101 @@
+package rdx_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestReader_UnreadMsg(t *testing.T) {
+	r := rdx.NewReader(strings.NewReader(":1\r\n:2\r\n"))
+	first, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v", err)
+	}
+
+	if err := r.UnreadMsg(first); err != nil {
+		t.Fatalf("UnreadMsg() err = %v", err)
+	}
+	if err := r.UnreadMsg(first); err != rdx.ErrInvalidUnreadMsg {
+		t.Fatalf("UnreadMsg() err = %v; want %v", err, rdx.ErrInvalidUnreadMsg)
+	}
+
+	pending := rdx.Array{rdx.BulkString("a")}
+	r.Reset(strings.NewReader(":2\r\n"))
+	if err := r.UnreadMsg(pending); err != nil {
+		t.Fatalf("UnreadMsg() after Reset err = %v", err)
+	}
+	if !r.More() {
+		t.Fatal("More() = false; want true")
+	}
+	if typ, err := r.PeekType(); err != nil || typ != rdx.TArray {
+		t.Fatalf("PeekType() = %v, %v; want %v, nil", typ, err, rdx.TArray)
+	}
+
+	for i, want := range []rdx.Msg{pending, rdx.Int(2)} {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("[%d] Read() err = %v", i, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Fatalf("[%d] Read() = %#v; want %#v", i, got, want)
+		}
+	}
+	if r.More() {
+		t.Fatal("More() = true; want false")
+	}
+}
+
+func TestReader_UnreadMsgCommand(t *testing.T) {
+	r := rdx.NewReader(strings.NewReader(""))
+	if err := r.UnreadMsg(rdx.Command("SET", "k", "v")); err != nil {
+		t.Fatalf("UnreadMsg() err = %v", err)
+	}
+
+	name, rest, err := r.ReadCommandName()
+	if err != nil || name != "SET" {
+		t.Fatalf("ReadCommandName() = %q, %v; want %q, nil", name, err, "SET")
+	}
+
+	k, err := rest.Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v", err)
+	}
+	if err := rest.UnreadMsg(k); err != nil {
+		t.Fatalf("UnreadMsg() err = %v", err)
+	}
+
+	args, err := rest.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() err = %v", err)
+	}
+	want := []rdx.Msg{rdx.String("k"), rdx.String("v")}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("ReadAll() = %#v; want %#v", args, want)
+	}
+
+	if err := r.UnreadMsg(rdx.Int(1)); err != nil {
+		t.Fatalf("UnreadMsg() err = %v", err)
+	}
+	if _, _, err := r.ReadCommandName(); err != rdx.ErrNotCommand {
+		t.Fatalf("ReadCommandName() err = %v; want %v", err, rdx.ErrNotCommand)
+	}
+}
+
+func TestReader_UnreadMsgCommand_options(t *testing.T) {
+	// The arguments of an unread command are read with the options of r.
+	r := rdx.NewReader(strings.NewReader(""))
+	r.MaxBulkSize = 1
+	if err := r.UnreadMsg(rdx.Command("GET", "kk")); err != nil {
+		t.Fatalf("UnreadMsg() err = %v", err)
+	}
+
+	_, rest, err := r.ReadCommandName()
+	if err != nil {
+		t.Fatalf("ReadCommandName() err = %v", err)
+	}
+	if _, err := rest.Read(); err != rdx.ErrBulkTooLarge {
+		t.Fatalf("rest.Read() err = %v; want %v", err, rdx.ErrBulkTooLarge)
+	}
+}
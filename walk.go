@@ -0,0 +1,49 @@
+package rdx
+
+// Walk returns a copy of m in which every message has been replaced by the result of fn. The
+// elements of arrays, sets, and pushes, the keys and values of maps, and the attributes and
+// message of an Attributed are walked first, depth-first, and fn is then called with a new
+// aggregate holding their replacements. fn is called on every message, leaves and aggregates
+// alike, and may return the message it was given to keep it, a different message to
+// substitute it, or Nil to drop its value. A dropped element leaves Nil in its place, so that
+// arrays keep their length and maps keep their pairs. A nil Msg is walked as Nil.
+//
+// Walk does not modify m: aggregates are always rebuilt.
+func Walk(m Msg, fn func(m Msg) Msg) Msg {
+	switch m := ensure(m).(type) {
+	case Array:
+		return fn(walkElems(m, fn))
+	case Set:
+		return fn(Set(walkElems(m, fn)))
+	case Push:
+		return fn(Push(walkElems(m, fn)))
+	case Map:
+		return fn(walkPairs(m, fn))
+	case Attributed:
+		return fn(Attributed{Attrs: walkPairs(m.Attrs, fn), Msg: Walk(m.Msg, fn)})
+	default:
+		return fn(m)
+	}
+}
+
+func walkElems(elems []Msg, fn func(Msg) Msg) Array {
+	if elems == nil {
+		return nil
+	}
+	walked := make(Array, len(elems))
+	for i, elem := range elems {
+		walked[i] = Walk(elem, fn)
+	}
+	return walked
+}
+
+func walkPairs(pairs Map, fn func(Msg) Msg) Map {
+	if pairs == nil {
+		return nil
+	}
+	walked := make(Map, len(pairs))
+	for i, p := range pairs {
+		walked[i] = Pair{Key: Walk(p.Key, fn), Value: Walk(p.Value, fn)}
+	}
+	return walked
+}
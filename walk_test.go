@@ -0,0 +1,83 @@
+package rdx_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+func TestWalk(t *testing.T) {
+	msg := rdx.Array{
+		rdx.BulkString("AUTH"),
+		rdx.Map{
+			{Key: rdx.BulkString("user"), Value: rdx.BulkString("alice")},
+			{Key: rdx.BulkString("password"), Value: rdx.BulkString("hunter2")},
+		},
+		rdx.Set{rdx.Int(1), rdx.Push{rdx.BulkString("password:x")}},
+		rdx.Attributed{
+			Attrs: rdx.Map{{Key: rdx.SimpleString("ttl"), Value: rdx.Int(3)}},
+			Msg:   rdx.BulkString("password:y"),
+		},
+		rdx.Array(nil),
+		nil,
+	}
+	orig := rdx.Array(append([]rdx.Msg(nil), msg...))
+
+	var visited []string
+	got := rdx.Walk(msg, func(m rdx.Msg) rdx.Msg {
+		visited = append(visited, m.String())
+		switch {
+		case rdx.IsA(m, rdx.TInt):
+			return rdx.Int(10)
+		case rdx.IsA(m, rdx.TString) && strings.HasPrefix(m.String(), "password:"):
+			return rdx.Nil
+		case rdx.Equal(m, rdx.BulkString("hunter2")):
+			return rdx.BulkString("***")
+		}
+		return m
+	})
+
+	want := rdx.Array{
+		rdx.BulkString("AUTH"),
+		rdx.Map{
+			{Key: rdx.BulkString("user"), Value: rdx.BulkString("alice")},
+			{Key: rdx.BulkString("password"), Value: rdx.BulkString("***")},
+		},
+		rdx.Set{rdx.Int(10), rdx.Push{rdx.Nil}},
+		rdx.Attributed{
+			Attrs: rdx.Map{{Key: rdx.SimpleString("ttl"), Value: rdx.Int(10)}},
+			Msg:   rdx.Nil,
+		},
+		rdx.Array(nil),
+		rdx.Nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Walk() =\n%#v\nwant\n%#v", got, want)
+	}
+	if !reflect.DeepEqual(msg, orig) {
+		t.Fatalf("Walk() modified its message: %#v", msg)
+	}
+
+	// Every message is visited, children before their aggregate.
+	if n := len(visited); n != 17 {
+		t.Fatalf("visited %d messages; want 17: %q", n, visited)
+	}
+	if visited[0] != "AUTH" {
+		t.Fatalf("first visited = %q; want AUTH", visited[0])
+	}
+}
+
+func TestWalk_ReplaceAggregate(t *testing.T) {
+	got := rdx.Walk(rdx.Array{rdx.Array{rdx.Int(1)}, rdx.Int(2)}, func(m rdx.Msg) rdx.Msg {
+		if ary, ok := m.(rdx.Array); ok && len(ary) == 1 {
+			return rdx.Int(len(ary))
+		}
+		return m
+	})
+	want := rdx.Array{rdx.Int(1), rdx.Int(2)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Walk() = %#v; want %#v", got, want)
+	}
+}
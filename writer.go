@@ -0,0 +1,345 @@
+package rdx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Writer is a buffered writer of messages. Messages written to a Writer are buffered until
+// Flush is called, allowing a pipeline of messages to be sent in a single write to the
+// underlying io.Writer.
+type Writer struct {
+	// MaxPending, if greater than zero, is the number of messages that may be written with
+	// WriteMsg before the Writer flushes automatically. This bounds the latency of a pipeline
+	// independently of the size of its messages. The count is reset by every flush.
+	MaxPending int
+
+	// MaxWriteSize, if greater than zero, is the largest number of bytes passed to a single
+	// call to Write on the underlying io.Writer. Larger writes are split into chunks of at most
+	// MaxWriteSize bytes, for transports that limit the size of a write. The bytes written are
+	// the same either way.
+	MaxWriteSize int
+
+	w       *bufio.Writer
+	dst     io.Writer
+	scratch bytes.Buffer // encoding buffer for single messages
+	proto   Protocol
+	pending int // messages written since the last flush
+}
+
+// NewWriter allocates a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	wr := &Writer{dst: w}
+	wr.w = bufio.NewWriter((*chunkWriter)(wr))
+	return wr
+}
+
+// Reset discards any unflushed data and switches the Writer to writing to w.
+func (w *Writer) Reset(wr io.Writer) {
+	w.dst = wr
+	w.w.Reset((*chunkWriter)(w))
+	w.scratch.Reset()
+	w.pending = 0
+}
+
+// WriteMsg writes msg to the Writer's buffer. If msg cannot be encoded, nothing is written.
+// Buffered data may be flushed to the underlying io.Writer if the buffer fills, and is
+// flushed once MaxPending messages have been written.
+func (w *Writer) WriteMsg(msg Msg) error {
+	if w.proto == RESP3 {
+		msg, _ = resp3Msg(msg)
+	} else {
+		var err error
+		if msg, _, err = resp2Msg(msg); err != nil {
+			return err
+		}
+	}
+
+	w.scratch.Reset()
+	if err := writeElem(&w.scratch, msg); err != nil {
+		return err
+	}
+	_, err := w.w.Write(w.scratch.Bytes())
+
+	// Release the encoding buffer if a large message grew it.
+	trimbuffer(&w.scratch)
+	if err != nil {
+		return err
+	}
+
+	w.pending++
+	if w.MaxPending > 0 && w.pending >= w.MaxPending {
+		return w.Flush()
+	}
+	return nil
+}
+
+// WriteArrayHeader writes the header of an array of n elements to the Writer's buffer. See the
+// WriteArrayHeader function.
+func (w *Writer) WriteArrayHeader(n int) error {
+	return WriteArrayHeader(w.w, n)
+}
+
+// WriteMapHeader writes the header of a map of n pairs to the Writer's buffer. See the
+// WriteMapHeader function. If the Writer's protocol is RESP2, ErrRESP3Only is returned.
+func (w *Writer) WriteMapHeader(n int) error {
+	if w.proto != RESP3 {
+		return ErrRESP3Only
+	}
+	return WriteMapHeader(w.w, n)
+}
+
+// Flush writes any buffered data to the underlying io.Writer and resets the count of pending
+// messages.
+func (w *Writer) Flush() error {
+	w.pending = 0
+	return w.w.Flush()
+}
+
+// Buffered returns the number of bytes buffered and not yet flushed.
+func (w *Writer) Buffered() int {
+	return w.w.Buffered()
+}
+
+// chunkWriter writes to a Writer's underlying io.Writer, splitting writes larger than the
+// Writer's MaxWriteSize.
+type chunkWriter Writer
+
+func (c *chunkWriter) Write(p []byte) (n int, err error) {
+	size := c.MaxWriteSize
+	if size <= 0 {
+		return c.dst.Write(p)
+	}
+	for len(p) > 0 {
+		chunk := p[:min(len(p), size)]
+		cn, err := c.dst.Write(chunk)
+		n += cn
+		if err != nil {
+			return n, err
+		} else if cn < len(chunk) {
+			return n, io.ErrShortWrite
+		}
+		p = p[cn:]
+	}
+	return n, nil
+}
+
+// WriteArrayHeader writes the header of an array of n elements to w, allowing a large array to
+// be written one element at a time, such as with Write, instead of being built in memory
+// first. The caller must write exactly n messages after the header, or the stream is left
+// corrupt. If n is negative, ErrNegativeLength is returned and nothing is written.
+func WriteArrayHeader(w io.Writer, n int) error {
+	return writeHeader(w, '*', n)
+}
+
+// WriteMapHeader writes the header of a RESP3 map of n pairs to w. As with WriteArrayHeader,
+// the caller must write exactly n pairs, each a key followed by its value, after the header.
+func WriteMapHeader(w io.Writer, n int) error {
+	return writeHeader(w, '%', n)
+}
+
+func writeHeader(w io.Writer, prefix byte, n int) error {
+	if n < 0 {
+		return ErrNegativeLength
+	}
+	var tmp [1 + 20 + 2]byte
+	b := strconv.AppendInt(append(tmp[:0], prefix), int64(n), 10)
+	_, err := w.Write(append(b, '\r', '\n'))
+	return err
+}
+
+// StreamingEncoder writes messages to an io.Writer, flushing the writer after each message so
+// that every message is delivered as soon as it is written. It is intended for streaming
+// responses, such as an http.ResponseWriter serving server-sent events.
+//
+// If the writer has a Flush() method, as with http.Flusher, or a Flush() error method, as with
+// bufio.Writer and Writer, it is called after each message. Otherwise, flushing is a no-op.
+type StreamingEncoder struct {
+	w       io.Writer
+	scratch bytes.Buffer
+}
+
+// NewStreamingEncoder allocates a new StreamingEncoder that writes to w.
+func NewStreamingEncoder(w io.Writer) *StreamingEncoder {
+	return &StreamingEncoder{w: w}
+}
+
+// WriteMsg writes msg to the underlying writer in a single call to Write and flushes it. If msg
+// cannot be encoded, nothing is written.
+func (e *StreamingEncoder) WriteMsg(msg Msg) error {
+	e.scratch.Reset()
+	if err := writeElem(&e.scratch, msg); err != nil {
+		return err
+	}
+	_, err := e.w.Write(e.scratch.Bytes())
+
+	trimbuffer(&e.scratch)
+	if err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// Flush flushes the underlying writer, if it supports flushing.
+func (e *StreamingEncoder) Flush() error {
+	switch f := e.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+// PipelineWriter accumulates encoded messages and writes them to an io.Writer in batches. Unlike
+// Writer, it only ever writes whole messages: its buffer grows to hold each message in full
+// and is flushed between messages, once the buffered data reaches a threshold or Flush is
+// called. This allows a server to coalesce many small replies into few writes without
+// sending part of a reply.
+type PipelineWriter struct {
+	w         io.Writer
+	buf       bytes.Buffer
+	threshold int
+}
+
+// NewPipelineWriter allocates a new PipelineWriter that writes to w. If threshold is greater
+// than zero, the PipelineWriter flushes after any message that brings the amount of buffered
+// data to at least threshold bytes. Otherwise, messages are only written by Flush.
+func NewPipelineWriter(w io.Writer, threshold int) *PipelineWriter {
+	return &PipelineWriter{w: w, threshold: threshold}
+}
+
+// Reset discards any unflushed data and switches the PipelineWriter to writing to w.
+func (p *PipelineWriter) Reset(w io.Writer) {
+	p.w = w
+	p.buf.Reset()
+}
+
+// WriteMsg appends msg to the buffer, flushing the buffer if it reaches the threshold. If msg
+// cannot be encoded, nothing is buffered.
+func (p *PipelineWriter) WriteMsg(msg Msg) error {
+	end := p.buf.Len()
+	if err := writeElem(&p.buf, msg); err != nil {
+		p.buf.Truncate(end)
+		return err
+	}
+	if p.threshold > 0 && p.buf.Len() >= p.threshold {
+		return p.Flush()
+	}
+	return nil
+}
+
+// Flush writes all buffered messages to the underlying io.Writer. If the write fails, the data
+// that was not written remains buffered, so Flush may be called again to retry.
+func (p *PipelineWriter) Flush() error {
+	_, err := p.buf.WriteTo(p.w)
+	return err
+}
+
+// Buffered returns the number of bytes buffered and not yet flushed.
+func (p *PipelineWriter) Buffered() int {
+	return p.buf.Len()
+}
+
+var ErrBulkWriterClosed = errors.New("rdx: BulkWriter is closed")
+
+// bulkHeaderLen is the length of the longest bulk string header, "$<max int64>\r\n".
+const bulkHeaderLen = 1 + 19 + 2
+
+// BulkWriter is an io.WriteCloser that collects the bytes written to it and, when closed,
+// writes them to an underlying io.Writer as a single bulk string. This allows a bulk string to
+// be built incrementally, such as by a logger or an encoder, without knowing its length in
+// advance. The payload is held in a pooled buffer until Close is called.
+type BulkWriter struct {
+	w      io.Writer
+	buf    *bytes.Buffer // header space followed by the payload; nil until the first Write
+	closed bool
+}
+
+// NewBulkWriter allocates a new BulkWriter that writes a bulk string to w when closed.
+func NewBulkWriter(w io.Writer) *BulkWriter {
+	return &BulkWriter{w: w}
+}
+
+// Write appends p to the bulk string's payload. It returns ErrBulkWriterClosed if the
+// BulkWriter has been closed.
+func (b *BulkWriter) Write(p []byte) (int, error) {
+	if b.closed {
+		return 0, ErrBulkWriterClosed
+	}
+	if b.buf == nil {
+		b.buf = tempbuffer(bulkHeaderLen + len(p) + 2)
+		// Reserve room for the header, which is filled in once the length is known.
+		b.buf.Write(make([]byte, bulkHeaderLen))
+	}
+	return b.buf.Write(p)
+}
+
+// Close writes the bulk string holding everything written to b to the underlying io.Writer in
+// a single call to Write. If nothing was written, an empty bulk string is written. Calling
+// Close more than once returns ErrBulkWriterClosed and writes nothing.
+func (b *BulkWriter) Close() error {
+	if b.closed {
+		return ErrBulkWriterClosed
+	}
+	b.closed = true
+	if b.buf == nil {
+		_, err := b.w.Write([]byte("$0\r\n\r\n"))
+		return err
+	}
+	defer func() {
+		putbuffer(b.buf)
+		b.buf = nil
+	}()
+
+	var tmp [bulkHeaderLen]byte
+	head := strconv.AppendInt(append(tmp[:0], '$'), int64(b.buf.Len()-bulkHeaderLen), 10)
+	head = append(head, '\r', '\n')
+
+	b.buf.WriteString("\r\n")
+	frame := b.buf.Bytes()[bulkHeaderLen-len(head):]
+	copy(frame, head)
+	_, err := b.w.Write(frame)
+	return err
+}
+
+// WriteBulkFrom writes a bulk string of length bytes read from src to w, without buffering
+// the payload. The header is written first, then exactly length bytes are copied from src,
+// followed by the trailing CRLF. It returns the number of bytes written to w. If src ends
+// before length bytes have been copied, WriteBulkFrom returns io.ErrUnexpectedEOF, and the
+// bulk string written to w is incomplete.
+func WriteBulkFrom(w io.Writer, length int64, src io.Reader) (n int64, err error) {
+	if length < 0 {
+		return 0, ErrNegativeLength
+	}
+
+	tmp := [23]byte{'$'}
+	head := strconv.AppendInt(tmp[:1], length, 10)
+	head = append(head, "\r\n"...)
+	hn, err := w.Write(head)
+	n += int64(hn)
+	if err != nil {
+		return n, err
+	}
+
+	cn, err := io.CopyN(w, src, length)
+	n += cn
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return n, err
+	}
+
+	tn, err := w.Write(crlf)
+	return n + int64(tn), err
+}
+
+// WriteBulkFrom writes a bulk string of length bytes read from src to the Writer's buffer, as
+// by the WriteBulkFrom function. Large payloads are written through to the underlying
+// io.Writer as the buffer fills.
+func (w *Writer) WriteBulkFrom(length int64, src io.Reader) (int64, error) {
+	return WriteBulkFrom(w.w, length, src)
+}
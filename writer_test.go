@@ -0,0 +1,455 @@
+package rdx_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+// countWriter records the number of calls to Write.
+type countWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.Buffer.Write(p)
+}
+
+func TestWriter(t *testing.T) {
+	var dst countWriter
+	w := rdx.NewWriter(&dst)
+
+	msgs := []rdx.Msg{
+		rdx.Array{rdx.BulkString("SET"), rdx.BulkString("k"), rdx.BulkString("v")},
+		rdx.Int(1),
+		nil,
+		rdx.SimpleString("OK"),
+	}
+	var want bytes.Buffer
+	for _, m := range msgs {
+		if err := w.WriteMsg(m); err != nil {
+			t.Fatalf("WriteMsg(%v) err = %v", m, err)
+		}
+		rdx.Write(&want, m)
+	}
+
+	// Invalid messages are not written.
+	if err := w.WriteMsg(rdx.Array{rdx.Int(1), rdx.Error("\n")}); err != rdx.ErrInvalidError {
+		t.Fatalf("WriteMsg() err = %v; want %v", err, rdx.ErrInvalidError)
+	}
+
+	if dst.writes != 0 {
+		t.Fatalf("writes before Flush = %d; want 0", dst.writes)
+	} else if w.Buffered() != want.Len() {
+		t.Fatalf("Buffered() = %d; want %d", w.Buffered(), want.Len())
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() err = %v", err)
+	}
+	if dst.writes != 1 {
+		t.Fatalf("writes after Flush = %d; want 1", dst.writes)
+	} else if dst.String() != want.String() {
+		t.Fatalf("wrote %q; want %q", dst.String(), want.String())
+	}
+
+	// Reset discards unflushed data.
+	var next bytes.Buffer
+	w.WriteMsg(rdx.Int(2))
+	w.Reset(&next)
+	w.WriteMsg(rdx.Int(3))
+	w.Flush()
+	if next.String() != ":3\r\n" {
+		t.Fatalf("wrote %q after Reset; want %q", next.String(), ":3\r\n")
+	}
+}
+
+func TestWriter_MaxPending(t *testing.T) {
+	var dst countWriter
+	w := rdx.NewWriter(&dst)
+	w.MaxPending = 3
+
+	write := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if err := w.WriteMsg(rdx.Int(i)); err != nil {
+				t.Fatalf("WriteMsg() err = %v", err)
+			}
+		}
+	}
+
+	write(2)
+	if dst.writes != 0 {
+		t.Fatalf("writes after 2 messages = %d; want 0", dst.writes)
+	}
+	write(1)
+	if dst.writes != 1 || w.Buffered() != 0 {
+		t.Fatalf("writes, Buffered() after 3 messages = %d, %d; want 1, 0", dst.writes, w.Buffered())
+	} else if want := ":0\r\n:1\r\n:0\r\n"; dst.String() != want {
+		t.Fatalf("wrote %q; want %q", dst.String(), want)
+	}
+
+	// The count restarts after an automatic flush.
+	write(2)
+	if dst.writes != 1 {
+		t.Fatalf("writes after 5 messages = %d; want 1", dst.writes)
+	}
+	write(1)
+	if dst.writes != 2 {
+		t.Fatalf("writes after 6 messages = %d; want 2", dst.writes)
+	}
+
+	// Messages that fail to encode are not counted.
+	write(2)
+	if err := w.WriteMsg(rdx.Error("\n")); err != rdx.ErrInvalidError {
+		t.Fatalf("WriteMsg() err = %v; want %v", err, rdx.ErrInvalidError)
+	} else if dst.writes != 2 {
+		t.Fatalf("writes after invalid message = %d; want 2", dst.writes)
+	}
+
+	// A manual flush also resets the count.
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() err = %v", err)
+	} else if dst.writes != 3 {
+		t.Fatalf("writes after Flush = %d; want 3", dst.writes)
+	}
+	write(2)
+	if dst.writes != 3 {
+		t.Fatalf("writes after Flush and 2 messages = %d; want 3", dst.writes)
+	}
+	write(1)
+	if dst.writes != 4 {
+		t.Fatalf("writes after Flush and 3 messages = %d; want 4", dst.writes)
+	}
+}
+
+// sizeWriter records the size of each call to Write.
+type sizeWriter struct {
+	bytes.Buffer
+	sizes []int
+}
+
+func (s *sizeWriter) Write(p []byte) (int, error) {
+	s.sizes = append(s.sizes, len(p))
+	return s.Buffer.Write(p)
+}
+
+func TestWriter_MaxWriteSize(t *testing.T) {
+	payload := strings.Repeat("x", 10000)
+	msgs := []rdx.Msg{rdx.BulkString(payload), rdx.Int(1), rdx.Array{rdx.BulkString(payload[:500])}}
+	var want bytes.Buffer
+	for _, m := range msgs {
+		rdx.Write(&want, m)
+	}
+
+	for _, size := range []int{0, 1000, 4096, 7, 1 << 20} {
+		var dst sizeWriter
+		w := rdx.NewWriter(&dst)
+		w.MaxWriteSize = size
+		for _, m := range msgs {
+			if err := w.WriteMsg(m); err != nil {
+				t.Fatalf("MaxWriteSize=%d: WriteMsg() err = %v", size, err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("MaxWriteSize=%d: Flush() err = %v", size, err)
+		}
+
+		if dst.String() != want.String() {
+			t.Errorf("MaxWriteSize=%d: wrote %d bytes that differ from the encoded messages", size, dst.Len())
+		}
+		for i, n := range dst.sizes {
+			if size > 0 && n > size {
+				t.Errorf("MaxWriteSize=%d: write %d has %d bytes", size, i, n)
+			}
+		}
+		// The reader sees the same messages regardless of chunking.
+		r := rdx.NewReader(&dst)
+		for _, m := range msgs {
+			if got, err := r.Read(); err != nil || !rdx.Equal(got, m) {
+				t.Errorf("MaxWriteSize=%d: Read() = %.20v, %v; want %.20v", size, got, err, m)
+			}
+		}
+	}
+
+	// A message larger than the buffer is split into the expected number of writes.
+	var dst sizeWriter
+	w := rdx.NewWriter(&dst)
+	w.MaxWriteSize = 1000
+	if err := w.WriteMsg(rdx.BulkString(payload)); err != nil {
+		t.Fatalf("WriteMsg() err = %v", err)
+	} else if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() err = %v", err)
+	}
+	// "$10000\r\n" + payload + "\r\n" is 10010 bytes: ten full writes and one of 10 bytes.
+	if want := []int{1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 10}; !reflect.DeepEqual(dst.sizes, want) {
+		t.Errorf("write sizes = %v; want %v", dst.sizes, want)
+	}
+}
+
+// flushRecorder records the output written between calls to Flush, like an
+// http.ResponseWriter that implements http.Flusher.
+type flushRecorder struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushed = append(f.flushed, f.String())
+	f.Reset()
+}
+
+func TestStreamingEncoder(t *testing.T) {
+	var rec flushRecorder
+	enc := rdx.NewStreamingEncoder(&rec)
+
+	msgs := []rdx.Msg{
+		rdx.Push{rdx.BulkString("message"), rdx.BulkString("ch"), rdx.BulkString("a")},
+		rdx.Int(1),
+		rdx.SimpleString("OK"),
+	}
+	var want []string
+	for _, m := range msgs {
+		if err := enc.WriteMsg(m); err != nil {
+			t.Fatalf("WriteMsg(%v) err = %v", m, err)
+		}
+		var buf bytes.Buffer
+		rdx.Write(&buf, m)
+		want = append(want, buf.String())
+	}
+
+	// Invalid messages are neither written nor flushed.
+	if err := enc.WriteMsg(rdx.Error("\n")); err != rdx.ErrInvalidError {
+		t.Fatalf("WriteMsg(invalid) err = %v; want %v", err, rdx.ErrInvalidError)
+	}
+
+	if !reflect.DeepEqual(rec.flushed, want) {
+		t.Fatalf("flushed = %q; want %q", rec.flushed, want)
+	}
+	if rec.Len() != 0 {
+		t.Fatalf("unflushed = %q; want none", rec.String())
+	}
+
+	// Writers that can't flush are written to directly.
+	var buf bytes.Buffer
+	if err := rdx.NewStreamingEncoder(&buf).WriteMsg(rdx.Int(2)); err != nil || buf.String() != ":2\r\n" {
+		t.Fatalf("WriteMsg(2) = %q, %v; want %q, nil", buf.String(), err, ":2\r\n")
+	}
+}
+
+func TestWriteBulkFrom(t *testing.T) {
+	payload := strings.Repeat("0123456789", 1000)
+	want := "$10000\r\n" + payload + "\r\n"
+
+	var buf bytes.Buffer
+	n, err := rdx.WriteBulkFrom(&buf, int64(len(payload)), strings.NewReader(payload+"extra"))
+	if err != nil || n != int64(len(want)) || buf.String() != want {
+		t.Fatalf("WriteBulkFrom() = %d, %v; wrote %.20q; want %d, nil", n, err, buf.String(), len(want))
+	}
+
+	buf.Reset()
+	if n, err := rdx.WriteBulkFrom(&buf, 0, strings.NewReader("")); err != nil || buf.String() != "$0\r\n\r\n" || n != 6 {
+		t.Fatalf("WriteBulkFrom(0) = %d, %v; wrote %q; want 6, nil", n, err, buf.String())
+	}
+
+	buf.Reset()
+	if n, err := rdx.WriteBulkFrom(&buf, 10, strings.NewReader("short")); err != io.ErrUnexpectedEOF || n != 10 {
+		t.Fatalf("WriteBulkFrom(short) = %d, %v; want 10, %v", n, err, io.ErrUnexpectedEOF)
+	}
+	if _, err := rdx.WriteBulkFrom(&buf, -1, strings.NewReader("")); err != rdx.ErrNegativeLength {
+		t.Fatalf("WriteBulkFrom(-1) err = %v; want %v", err, rdx.ErrNegativeLength)
+	}
+
+	// Through a Writer, the bulk string is read back intact.
+	buf.Reset()
+	w := rdx.NewWriter(&buf)
+	w.WriteMsg(rdx.Int(1))
+	if _, err := w.WriteBulkFrom(int64(len(payload)), strings.NewReader(payload)); err != nil {
+		t.Fatalf("Writer.WriteBulkFrom() err = %v", err)
+	}
+	w.Flush()
+	if got := buf.String(); got != ":1\r\n"+want {
+		t.Fatalf("Writer.WriteBulkFrom() wrote %.20q; want %.20q", got, ":1\r\n"+want)
+	}
+}
+
+// shortWriter writes at most n bytes before failing.
+type shortWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (s *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > s.n {
+		s.Buffer.Write(p[:s.n])
+		n := s.n
+		s.n = 0
+		return n, io.ErrShortWrite
+	}
+	s.n -= len(p)
+	return s.Buffer.Write(p)
+}
+
+func TestPipelineWriter(t *testing.T) {
+	var dst countWriter
+	w := rdx.NewPipelineWriter(&dst, 16)
+
+	// ":1\r\n" is 4 bytes and "$10\r\n0123456789\r\n" is 17, so the threshold is crossed by the
+	// third message and everything up to it is written at once.
+	msgs := []rdx.Msg{rdx.Int(1), rdx.Int(2), rdx.BulkString("0123456789"), rdx.Int(3)}
+	var want bytes.Buffer
+	for i, m := range msgs {
+		if err := w.WriteMsg(m); err != nil {
+			t.Fatalf("WriteMsg(%v) err = %v", m, err)
+		}
+		if i < 3 {
+			rdx.Write(&want, m)
+		}
+	}
+	if dst.writes != 1 || dst.String() != want.String() {
+		t.Fatalf("wrote %q in %d writes; want %q in 1", dst.String(), dst.writes, want.String())
+	}
+	if n := w.Buffered(); n != 4 {
+		t.Fatalf("Buffered() = %d; want 4", n)
+	}
+
+	// A message that fails to encode leaves the buffer unchanged.
+	if err := w.WriteMsg(rdx.Array{rdx.Int(4), rdx.Error("\r\n")}); err != rdx.ErrInvalidError {
+		t.Fatalf("WriteMsg() err = %v; want %v", err, rdx.ErrInvalidError)
+	} else if n := w.Buffered(); n != 4 {
+		t.Fatalf("Buffered() = %d after failed write; want 4", n)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() err = %v", err)
+	}
+	want.WriteString(":3\r\n")
+	if dst.writes != 2 || dst.String() != want.String() || w.Buffered() != 0 {
+		t.Fatalf("wrote %q in %d writes, %d buffered; want %q in 2, 0 buffered", dst.String(), dst.writes, w.Buffered(), want.String())
+	}
+
+	// Unwritten data remains buffered after a failed flush.
+	short := &shortWriter{n: 2}
+	w.Reset(short)
+	w.WriteMsg(rdx.Int(5))
+	if err := w.Flush(); err != io.ErrShortWrite {
+		t.Fatalf("Flush() err = %v; want %v", err, io.ErrShortWrite)
+	} else if n := w.Buffered(); n != 2 {
+		t.Fatalf("Buffered() = %d after short write; want 2", n)
+	}
+	short.n = 2
+	if err := w.Flush(); err != nil || short.String() != ":5\r\n" {
+		t.Fatalf("Flush() = %v, wrote %q; want nil, %q", err, short.String(), ":5\r\n")
+	}
+}
+
+func TestWriteArrayHeader(t *testing.T) {
+	fields := []string{"f1", "v1", "f2", "v2"}
+
+	var buf bytes.Buffer
+	if err := rdx.WriteArrayHeader(&buf, len(fields)); err != nil {
+		t.Fatalf("WriteArrayHeader() err = %v", err)
+	}
+	for _, f := range fields {
+		if _, err := rdx.Write(&buf, rdx.BulkString(f)); err != nil {
+			t.Fatalf("Write(%q) err = %v", f, err)
+		}
+	}
+	if err := rdx.WriteMapHeader(&buf, 1); err != nil {
+		t.Fatalf("WriteMapHeader() err = %v", err)
+	}
+	rdx.Write(&buf, rdx.SimpleString("k"))
+	rdx.Write(&buf, rdx.Int(1))
+
+	r := newRESP3Reader(&buf)
+	msgs, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() err = %v", err)
+	}
+	want := []rdx.Msg{
+		rdx.Array{rdx.String("f1"), rdx.String("v1"), rdx.String("f2"), rdx.String("v2")},
+		rdx.Map{{Key: rdx.String("k"), Value: rdx.Int(1)}},
+	}
+	if !reflect.DeepEqual(msgs, want) {
+		t.Fatalf("ReadAll() = %v; want %v", msgs, want)
+	}
+
+	buf.Reset()
+	if err := rdx.WriteArrayHeader(&buf, -1); err != rdx.ErrNegativeLength || buf.Len() != 0 {
+		t.Fatalf("WriteArrayHeader(-1) err = %v, wrote %q; want %v, nothing", err, buf.String(), rdx.ErrNegativeLength)
+	}
+}
+
+func TestWriter_WriteHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	w := rdx.NewWriter(&buf)
+	w.SetProtocol(rdx.RESP3)
+	if err := w.WriteArrayHeader(2); err != nil {
+		t.Fatalf("WriteArrayHeader() err = %v", err)
+	}
+	w.WriteMsg(rdx.Int(1))
+	w.WriteMsg(rdx.Int(2))
+	if err := w.WriteMapHeader(0); err != nil {
+		t.Fatalf("WriteMapHeader() err = %v", err)
+	}
+	w.SetProtocol(rdx.RESP2)
+	if err := w.WriteMapHeader(0); err != rdx.ErrRESP3Only {
+		t.Fatalf("WriteMapHeader() in RESP2 err = %v; want %v", err, rdx.ErrRESP3Only)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() err = %v", err)
+	}
+	if want := "*2\r\n:1\r\n:2\r\n%0\r\n"; buf.String() != want {
+		t.Fatalf("wrote %q; want %q", buf.String(), want)
+	}
+}
+
+func TestBulkWriter(t *testing.T) {
+	var dst countWriter
+	bw := rdx.NewBulkWriter(&dst)
+
+	var want strings.Builder
+	for i := 0; i < 100; i++ {
+		line := strings.Repeat("x", i) + "\n"
+		if n, err := io.WriteString(bw, line); err != nil || n != len(line) {
+			t.Fatalf("Write() = %d, %v; want %d, nil", n, err, len(line))
+		}
+		want.WriteString(line)
+	}
+	if dst.writes != 0 {
+		t.Fatalf("%d writes before Close; want 0", dst.writes)
+	}
+
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() err = %v", err)
+	}
+	if dst.writes != 1 {
+		t.Fatalf("Close() made %d writes; want 1", dst.writes)
+	}
+	msg, err := rdx.NewReader(&dst.Buffer).Read()
+	if err != nil || msg.String() != want.String() {
+		t.Fatalf("Read() = %.20q, %v; want %.20q, nil", msg, err, want.String())
+	}
+
+	if err := bw.Close(); err != rdx.ErrBulkWriterClosed {
+		t.Fatalf("second Close() err = %v; want %v", err, rdx.ErrBulkWriterClosed)
+	}
+	if _, err := bw.Write([]byte("x")); err != rdx.ErrBulkWriterClosed {
+		t.Fatalf("Write() after Close err = %v; want %v", err, rdx.ErrBulkWriterClosed)
+	}
+	if dst.writes != 1 {
+		t.Fatalf("%d writes after Close; want 1", dst.writes)
+	}
+
+	// A BulkWriter with nothing written to it writes an empty bulk string.
+	var buf bytes.Buffer
+	if err := rdx.NewBulkWriter(&buf).Close(); err != nil || buf.String() != "$0\r\n\r\n" {
+		t.Fatalf("Close() err = %v, wrote %q; want nil, %q", err, buf.String(), "$0\r\n\r\n")
+	}
+}
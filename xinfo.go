@@ -0,0 +1,371 @@
+package rdx
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// StreamInfo is the parsed reply of an XINFO STREAM command. Fields not present in the reply
+// are left as their zero values.
+type StreamInfo struct {
+	Length               int64
+	RadixTreeKeys        int64
+	RadixTreeNodes       int64
+	LastGeneratedID      string
+	MaxDeletedEntryID    string
+	EntriesAdded         int64
+	RecordedFirstEntryID string
+
+	// FirstEntry and LastEntry are only set by the non-FULL form of XINFO STREAM.
+	FirstEntry *StreamEntry
+	LastEntry  *StreamEntry
+
+	// Entries and Groups are only set by the FULL form of XINFO STREAM.
+	Entries []StreamEntry
+	Groups  []StreamGroupInfo
+}
+
+// StreamEntry is a single entry of a stream.
+type StreamEntry struct {
+	ID     string
+	Fields []StreamField
+}
+
+// StreamField is a single field of a stream entry.
+type StreamField struct {
+	Name  string
+	Value string
+}
+
+// StreamGroupInfo describes a consumer group in the FULL form of XINFO STREAM.
+type StreamGroupInfo struct {
+	Name            string
+	LastDeliveredID string
+	EntriesRead     int64
+	Lag             int64
+	PELCount        int64
+	PEL             []StreamPendingEntry
+	Consumers       []StreamConsumerInfo
+}
+
+// StreamConsumerInfo describes a consumer of a consumer group in the FULL form of XINFO
+// STREAM.
+type StreamConsumerInfo struct {
+	Name       string
+	SeenTime   int64
+	ActiveTime int64
+	PELCount   int64
+	PEL        []StreamPendingEntry
+}
+
+// StreamPendingEntry is an entry of a pending entries list. Times are in milliseconds since
+// the Unix epoch. Consumer is empty for entries of a consumer's PEL.
+type StreamPendingEntry struct {
+	ID            string
+	Consumer      string
+	DeliveryTime  int64
+	DeliveryCount int64
+}
+
+// ParseXInfoStream parses the reply of an XINFO STREAM command, with or without the FULL
+// option. Both the RESP2 form (flat arrays of alternating keys and values) and the RESP3 form
+// (maps) are accepted. Unknown keys are ignored.
+func ParseXInfoStream(m Msg) (*StreamInfo, error) {
+	pairs, err := infoPairs(m)
+	if err != nil {
+		return nil, fmt.Errorf("rdx: xinfo stream: %w", err)
+	}
+
+	info := new(StreamInfo)
+	for _, p := range pairs {
+		key := ensure(p.Key).String()
+		switch key {
+		case "length":
+			info.Length, err = infoInt(p.Value)
+		case "radix-tree-keys":
+			info.RadixTreeKeys, err = infoInt(p.Value)
+		case "radix-tree-nodes":
+			info.RadixTreeNodes, err = infoInt(p.Value)
+		case "last-generated-id":
+			info.LastGeneratedID, err = infoString(p.Value)
+		case "max-deleted-entry-id":
+			info.MaxDeletedEntryID, err = infoString(p.Value)
+		case "entries-added":
+			info.EntriesAdded, err = infoInt(p.Value)
+		case "recorded-first-entry-id":
+			info.RecordedFirstEntryID, err = infoString(p.Value)
+		case "first-entry":
+			info.FirstEntry, err = parseOptStreamEntry(p.Value)
+		case "last-entry":
+			info.LastEntry, err = parseOptStreamEntry(p.Value)
+		case "entries":
+			info.Entries, err = parseStreamEntries(p.Value)
+		case "groups":
+			// The non-FULL form replies with the number of groups instead.
+			if _, ok := p.Value.(Int); ok {
+				continue
+			}
+			info.Groups, err = parseStreamGroups(p.Value)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("rdx: xinfo stream %s: %w", key, err)
+		}
+	}
+
+	return info, nil
+}
+
+func parseOptStreamEntry(m Msg) (*StreamEntry, error) {
+	if IsA(m, TNil) {
+		return nil, nil
+	}
+	entry, err := parseStreamEntry(m)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func parseStreamEntries(m Msg) ([]StreamEntry, error) {
+	elems, err := infoArray(m)
+	if err != nil || len(elems) == 0 {
+		return nil, err
+	}
+
+	entries := make([]StreamEntry, len(elems))
+	for i, elem := range elems {
+		if entries[i], err = parseStreamEntry(elem); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func parseStreamEntry(m Msg) (entry StreamEntry, err error) {
+	elems, err := infoArray(m)
+	if err != nil {
+		return entry, err
+	} else if len(elems) != 2 {
+		return entry, fmt.Errorf("entry has %d elements; want 2", len(elems))
+	}
+
+	if entry.ID, err = infoString(elems[0]); err != nil {
+		return entry, err
+	}
+
+	// Deleted entries may have nil fields.
+	fields, err := infoArray(elems[1])
+	if err != nil {
+		return entry, err
+	} else if len(fields)%2 != 0 {
+		return entry, ErrOddMapLength
+	} else if len(fields) == 0 {
+		return entry, nil
+	}
+
+	entry.Fields = make([]StreamField, len(fields)/2)
+	for i := range entry.Fields {
+		f := &entry.Fields[i]
+		if f.Name, err = infoString(fields[i*2]); err != nil {
+			return entry, err
+		}
+		if f.Value, err = infoString(fields[i*2+1]); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+func parseStreamGroups(m Msg) ([]StreamGroupInfo, error) {
+	elems, err := infoArray(m)
+	if err != nil || len(elems) == 0 {
+		return nil, err
+	}
+
+	groups := make([]StreamGroupInfo, len(elems))
+	for i, elem := range elems {
+		if groups[i], err = parseStreamGroup(elem); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+func parseStreamGroup(m Msg) (group StreamGroupInfo, err error) {
+	pairs, err := infoPairs(m)
+	if err != nil {
+		return group, err
+	}
+
+	for _, p := range pairs {
+		key := ensure(p.Key).String()
+		switch key {
+		case "name":
+			group.Name, err = infoString(p.Value)
+		case "last-delivered-id":
+			group.LastDeliveredID, err = infoString(p.Value)
+		case "entries-read":
+			group.EntriesRead, err = infoInt(p.Value)
+		case "lag":
+			group.Lag, err = infoInt(p.Value)
+		case "pel-count":
+			group.PELCount, err = infoInt(p.Value)
+		case "pel":
+			group.PEL, err = parsePendingEntries(p.Value, true)
+		case "consumers":
+			group.Consumers, err = parseStreamConsumers(p.Value)
+		}
+
+		if err != nil {
+			return group, fieldError(key, err)
+		}
+	}
+	return group, nil
+}
+
+func parseStreamConsumers(m Msg) ([]StreamConsumerInfo, error) {
+	elems, err := infoArray(m)
+	if err != nil || len(elems) == 0 {
+		return nil, err
+	}
+
+	consumers := make([]StreamConsumerInfo, len(elems))
+	for i, elem := range elems {
+		if consumers[i], err = parseStreamConsumer(elem); err != nil {
+			return nil, err
+		}
+	}
+	return consumers, nil
+}
+
+func parseStreamConsumer(m Msg) (consumer StreamConsumerInfo, err error) {
+	pairs, err := infoPairs(m)
+	if err != nil {
+		return consumer, err
+	}
+
+	for _, p := range pairs {
+		key := ensure(p.Key).String()
+		switch key {
+		case "name":
+			consumer.Name, err = infoString(p.Value)
+		case "seen-time":
+			consumer.SeenTime, err = infoInt(p.Value)
+		case "active-time":
+			consumer.ActiveTime, err = infoInt(p.Value)
+		case "pel-count":
+			consumer.PELCount, err = infoInt(p.Value)
+		case "pel":
+			consumer.PEL, err = parsePendingEntries(p.Value, false)
+		}
+
+		if err != nil {
+			return consumer, fieldError(key, err)
+		}
+	}
+	return consumer, nil
+}
+
+// parsePendingEntries parses a PEL. Group PEL entries include the name of the consumer that
+// owns the entry, while consumer PEL entries do not.
+func parsePendingEntries(m Msg, withConsumer bool) ([]StreamPendingEntry, error) {
+	elems, err := infoArray(m)
+	if err != nil || len(elems) == 0 {
+		return nil, err
+	}
+
+	want := 3
+	if withConsumer {
+		want = 4
+	}
+
+	pel := make([]StreamPendingEntry, len(elems))
+	for i, elem := range elems {
+		fields, err := infoArray(elem)
+		if err != nil {
+			return nil, err
+		} else if len(fields) != want {
+			return nil, fmt.Errorf("pending entry has %d elements; want %d", len(fields), want)
+		}
+
+		pe := &pel[i]
+		if pe.ID, err = infoString(fields[0]); err != nil {
+			return nil, err
+		}
+		if withConsumer {
+			if pe.Consumer, err = infoString(fields[1]); err != nil {
+				return nil, err
+			}
+			fields = fields[1:]
+		}
+		if pe.DeliveryTime, err = infoInt(fields[1]); err != nil {
+			return nil, err
+		}
+		if pe.DeliveryCount, err = infoInt(fields[2]); err != nil {
+			return nil, err
+		}
+	}
+	return pel, nil
+}
+
+// fieldError wraps err with the name of the field it was returned for. Nested fields are
+// joined, e.g. "groups: consumers: name: ...", and the "rdx: xinfo stream" prefix is added only
+// by ParseXInfoStream.
+func fieldError(field string, err error) error {
+	return fmt.Errorf("%s: %w", field, err)
+}
+
+// infoPairs returns the key/value pairs of either a Map or a flat Array of alternating keys
+// and values.
+func infoPairs(m Msg) ([]Pair, error) {
+	switch m := ensure(m).(type) {
+	case Map:
+		return m, nil
+	case Array:
+		return m.OrderedPairs()
+	default:
+		return nil, fmt.Errorf("got %T; want map or array", m)
+	}
+}
+
+// infoArray returns the elements of an Array or Set. Nil is treated as an empty array.
+func infoArray(m Msg) ([]Msg, error) {
+	switch m := ensure(m).(type) {
+	case Array:
+		return m, nil
+	case Set:
+		return m, nil
+	case nilmsg:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("got %T; want array", m)
+	}
+}
+
+// infoString returns the string value of a string or integer. Nil is treated as an empty
+// string.
+func infoString(m Msg) (string, error) {
+	switch m := ensure(m).(type) {
+	case String, BulkString, SimpleString, Int:
+		return m.String(), nil
+	case nilmsg:
+		return "", nil
+	default:
+		return "", fmt.Errorf("got %T; want string", m)
+	}
+}
+
+// infoInt returns the integer value of an integer or numeric string. Nil is treated as zero.
+func infoInt(m Msg) (int64, error) {
+	switch m := ensure(m).(type) {
+	case Int:
+		return int64(m), nil
+	case String, BulkString, SimpleString:
+		return strconv.ParseInt(m.String(), 10, 64)
+	case nilmsg:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("got %T; want integer", m)
+	}
+}
@@ -0,0 +1,225 @@
+package rdx_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.spiff.io/rdx"
+)
+
+// kvfunc builds a key/value reply from alternating keys and values.
+type kvfunc func(kvs ...rdx.Msg) rdx.Msg
+
+func resp2KV(kvs ...rdx.Msg) rdx.Msg { return rdx.Array(kvs) }
+
+func resp3KV(kvs ...rdx.Msg) rdx.Msg {
+	m := make(rdx.Map, len(kvs)/2)
+	for i := range m {
+		m[i] = rdx.Pair{Key: kvs[i*2], Value: kvs[i*2+1]}
+	}
+	return m
+}
+
+func bulk(s string) rdx.Msg { return rdx.BulkString(s) }
+
+func xinfoStreamFull(kv kvfunc) rdx.Msg {
+	return kv(
+		bulk("length"), rdx.Int(2),
+		bulk("radix-tree-keys"), rdx.Int(1),
+		bulk("radix-tree-nodes"), rdx.Int(2),
+		bulk("last-generated-id"), bulk("1638125141232-0"),
+		bulk("max-deleted-entry-id"), bulk("0-0"),
+		bulk("entries-added"), rdx.Int(2),
+		bulk("recorded-first-entry-id"), bulk("1638125133432-0"),
+		bulk("unknown-future-key"), rdx.Array{rdx.Int(1), rdx.Int(2)},
+		bulk("entries"), rdx.Array{
+			rdx.Array{bulk("1638125133432-0"), rdx.Array{bulk("message"), bulk("apple")}},
+			rdx.Array{bulk("1638125141232-0"), rdx.Array{bulk("message"), bulk("banana"), bulk("n"), bulk("2")}},
+		},
+		bulk("groups"), rdx.Array{
+			kv(
+				bulk("name"), bulk("mygroup"),
+				bulk("last-delivered-id"), bulk("1638125141232-0"),
+				bulk("entries-read"), rdx.Int(2),
+				bulk("lag"), rdx.Int(0),
+				bulk("pel-count"), rdx.Int(2),
+				bulk("pel"), rdx.Array{
+					rdx.Array{bulk("1638125133432-0"), bulk("Alice"), rdx.Int(1638125153423), rdx.Int(1)},
+					rdx.Array{bulk("1638125141232-0"), bulk("Alice"), rdx.Int(1638125153423), rdx.Int(1)},
+				},
+				bulk("consumers"), rdx.Array{
+					kv(
+						bulk("name"), bulk("Alice"),
+						bulk("seen-time"), rdx.Int(1638125153423),
+						bulk("active-time"), rdx.Int(1638125153424),
+						bulk("pel-count"), rdx.Int(2),
+						bulk("pel"), rdx.Array{
+							rdx.Array{bulk("1638125133432-0"), rdx.Int(1638125153423), rdx.Int(1)},
+							rdx.Array{bulk("1638125141232-0"), rdx.Int(1638125153423), rdx.Int(1)},
+						},
+					),
+				},
+			),
+		},
+	)
+}
+
+var xinfoStreamFullWant = &rdx.StreamInfo{
+	Length:               2,
+	RadixTreeKeys:        1,
+	RadixTreeNodes:       2,
+	LastGeneratedID:      "1638125141232-0",
+	MaxDeletedEntryID:    "0-0",
+	EntriesAdded:         2,
+	RecordedFirstEntryID: "1638125133432-0",
+	Entries: []rdx.StreamEntry{
+		{ID: "1638125133432-0", Fields: []rdx.StreamField{{Name: "message", Value: "apple"}}},
+		{ID: "1638125141232-0", Fields: []rdx.StreamField{{Name: "message", Value: "banana"}, {Name: "n", Value: "2"}}},
+	},
+	Groups: []rdx.StreamGroupInfo{{
+		Name:            "mygroup",
+		LastDeliveredID: "1638125141232-0",
+		EntriesRead:     2,
+		Lag:             0,
+		PELCount:        2,
+		PEL: []rdx.StreamPendingEntry{
+			{ID: "1638125133432-0", Consumer: "Alice", DeliveryTime: 1638125153423, DeliveryCount: 1},
+			{ID: "1638125141232-0", Consumer: "Alice", DeliveryTime: 1638125153423, DeliveryCount: 1},
+		},
+		Consumers: []rdx.StreamConsumerInfo{{
+			Name:       "Alice",
+			SeenTime:   1638125153423,
+			ActiveTime: 1638125153424,
+			PELCount:   2,
+			PEL: []rdx.StreamPendingEntry{
+				{ID: "1638125133432-0", DeliveryTime: 1638125153423, DeliveryCount: 1},
+				{ID: "1638125141232-0", DeliveryTime: 1638125153423, DeliveryCount: 1},
+			},
+		}},
+	}},
+}
+
+// wireDecode encodes and decodes m so that fixtures are parsed in the form the Reader produces.
+func wireDecode(t *testing.T, m rdx.Msg) rdx.Msg {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := rdx.Write(&buf, m); err != nil {
+		t.Fatalf("Write(%v) err = %v", m, err)
+	}
+	dec, err := newRESP3Reader(&buf).Read()
+	if err != nil {
+		t.Fatalf("Read() err = %v", err)
+	}
+	return dec
+}
+
+func TestParseXInfoStream_full(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		kv   kvfunc
+	}{
+		{"RESP2", resp2KV},
+		{"RESP3", resp3KV},
+	} {
+		got, err := rdx.ParseXInfoStream(wireDecode(t, xinfoStreamFull(c.kv)))
+		if err != nil {
+			t.Errorf("%s: ParseXInfoStream() err = %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, xinfoStreamFullWant) {
+			t.Errorf("%s: ParseXInfoStream() =\n%+v\nwant\n%+v", c.name, got, xinfoStreamFullWant)
+		}
+	}
+}
+
+func TestParseXInfoStream_summary(t *testing.T) {
+	msg := resp3KV(
+		bulk("length"), rdx.Int(1),
+		bulk("last-generated-id"), bulk("1-0"),
+		bulk("groups"), rdx.Int(3),
+		bulk("first-entry"), rdx.Array{bulk("1-0"), rdx.Array{bulk("k"), bulk("v")}},
+		bulk("last-entry"), rdx.Nil,
+	)
+	want := &rdx.StreamInfo{
+		Length:          1,
+		LastGeneratedID: "1-0",
+		FirstEntry:      &rdx.StreamEntry{ID: "1-0", Fields: []rdx.StreamField{{Name: "k", Value: "v"}}},
+	}
+
+	got, err := rdx.ParseXInfoStream(wireDecode(t, msg))
+	if err != nil {
+		t.Fatalf("ParseXInfoStream() err = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseXInfoStream() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseXInfoStream_partial(t *testing.T) {
+	for i, c := range []struct {
+		msg  rdx.Msg
+		want *rdx.StreamInfo
+	}{
+		{rdx.Array(nil), &rdx.StreamInfo{}},
+		{rdx.Map(nil), &rdx.StreamInfo{}},
+		// Missing groups and a deleted entry with nil fields.
+		{
+			resp2KV(
+				bulk("length"), bulk("1"),
+				bulk("entries"), rdx.Array{rdx.Array{bulk("1-0"), rdx.Nil}},
+			),
+			&rdx.StreamInfo{Length: 1, Entries: []rdx.StreamEntry{{ID: "1-0"}}},
+		},
+		// A group without consumers and a nil lag.
+		{
+			resp3KV(bulk("groups"), rdx.Array{resp3KV(
+				bulk("name"), bulk("g"),
+				bulk("lag"), rdx.Nil,
+				bulk("consumers"), rdx.Array(nil),
+			)}),
+			&rdx.StreamInfo{Groups: []rdx.StreamGroupInfo{{Name: "g"}}},
+		},
+	} {
+		got, err := rdx.ParseXInfoStream(wireDecode(t, c.msg))
+		if err != nil {
+			t.Errorf("[%d] ParseXInfoStream() err = %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%d] ParseXInfoStream() =\n%+v\nwant\n%+v", i, got, c.want)
+		}
+	}
+}
+
+func TestParseXInfoStream_malformed(t *testing.T) {
+	for i, msg := range []rdx.Msg{
+		rdx.Int(1),
+		rdx.Nil,
+		resp2KV(bulk("length")),
+		resp2KV(bulk("length"), bulk("x")),
+		resp2KV(bulk("entries"), rdx.Array{rdx.Array{bulk("1-0")}}),
+		resp2KV(bulk("entries"), rdx.Array{rdx.Array{bulk("1-0"), rdx.Array{bulk("k")}}}),
+		resp2KV(bulk("groups"), rdx.Array{rdx.Int(1)}),
+		resp2KV(bulk("groups"), rdx.Array{resp2KV(bulk("pel"), rdx.Array{rdx.Array{bulk("1-0"), rdx.Int(1), rdx.Int(1)}})}),
+		resp2KV(bulk("groups"), rdx.Array{resp2KV(bulk("consumers"), rdx.Array{resp2KV(bulk("seen-time"), rdx.Array(nil))})}),
+	} {
+		if got, err := rdx.ParseXInfoStream(msg); err == nil {
+			t.Errorf("[%d] ParseXInfoStream() = %+v; want error", i, got)
+		}
+	}
+
+	_, err := rdx.ParseXInfoStream(resp2KV(bulk("entries"), rdx.Array{rdx.Array{bulk("1-0"), rdx.Array{bulk("k")}}}))
+	if !errors.Is(err, rdx.ErrOddMapLength) {
+		t.Errorf("ParseXInfoStream() err = %v; want ErrOddMapLength", err)
+	}
+
+	// Errors in nested fields are prefixed once.
+	_, err = rdx.ParseXInfoStream(resp2KV(bulk("groups"), rdx.Array{resp2KV(bulk("consumers"), rdx.Array{resp2KV(bulk("seen-time"), rdx.Array(nil))})}))
+	const prefix = "rdx: xinfo stream groups: consumers: seen-time: "
+	if err == nil || !strings.HasPrefix(err.Error(), prefix) || strings.Count(err.Error(), "rdx: ") != 1 {
+		t.Errorf("ParseXInfoStream() err = %v; want %q prefix only once", err, prefix)
+	}
+}